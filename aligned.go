@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// alignedSchedule fires every interval aligned to the clock from 00:00 of every day, like @aligned 15m at :00, :15, :30 and :45,
+// or @aligned 1h+5m at :05 of every hour. Unlike @every, the firings do not depend on when the process started.
+type alignedSchedule struct {
+	interval, offset time.Duration
+	loc              *time.Location
+}
+
+// parseAligned parses the schedule of @aligned, returns nil if not aligned
+func parseAligned(expr string) (*alignedSchedule, error) {
+	loc, fields, err := scheduleFields(expr)
+	if err != nil || len(fields) <= 0 || fields[0] != "@aligned" {
+		return nil, err
+	}
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("@aligned must be like @aligned 15m or @aligned 1h+5m")
+	}
+
+	s := &alignedSchedule{loc: loc}
+	interval, offset, _ := strings.Cut(fields[1], "+")
+	if s.interval, err = time.ParseDuration(interval); err != nil {
+		return nil, fmt.Errorf("invalid interval of @aligned: %w", err)
+	}
+	if offset != "" {
+		if s.offset, err = time.ParseDuration(offset); err != nil {
+			return nil, fmt.Errorf("invalid offset of @aligned: %w", err)
+		}
+	}
+	if s.interval < time.Second || s.interval > 24*time.Hour || s.interval%time.Second != 0 {
+		return nil, fmt.Errorf("the interval of @aligned must be whole seconds within [1s, 24h], yours: %s", interval)
+	}
+	if s.offset < 0 || s.offset >= s.interval {
+		return nil, fmt.Errorf("the offset of @aligned must be within [0, %s), yours: %s", interval, offset)
+	}
+	return s, nil
+}
+
+func (s *alignedSchedule) Next(t time.Time) time.Time {
+	// the firings of a day are until the offset of the next day, which may be after t
+	y, m, d := t.In(s.loc).Date()
+	for i := -1; i <= 1; i++ {
+		start := time.Date(y, m, d+i, 0, 0, 0, 0, s.loc).Add(s.offset)
+		end := time.Date(y, m, d+i+1, 0, 0, 0, 0, s.loc).Add(s.offset)
+
+		next := start
+		if !t.Before(start) {
+			next = start.Add((t.Sub(start)/s.interval + 1) * s.interval)
+		}
+		if next.Before(end) {
+			return next.In(t.Location())
+		}
+	}
+	return time.Time{}
+}
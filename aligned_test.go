@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAlignedNext(t *testing.T) {
+	tests := []struct {
+		expr string
+		from string
+		want string
+	}{
+		{"@aligned 15m", "2024-05-01T10:07:00Z", "2024-05-01T10:15:00Z"},
+		{"@aligned 15m", "2024-05-01T10:15:00Z", "2024-05-01T10:30:00Z"},
+		{"@aligned 1h+5m", "2024-05-01T10:07:00Z", "2024-05-01T11:05:00Z"},
+		{"@aligned 1h+5m", "2024-05-01T23:30:00Z", "2024-05-02T00:05:00Z"},
+		{"@aligned 7m", "2024-05-01T23:55:00Z", "2024-05-02T00:00:00Z"}, // aligned again at 00:00
+		{"@aligned 24h+2h", "2024-05-01T01:00:00Z", "2024-05-01T02:00:00Z"},
+		{"@aligned 24h+2h", "2024-05-01T02:00:00Z", "2024-05-02T02:00:00Z"},
+		{"@aligned 10s", "2024-05-01T10:00:05.5Z", "2024-05-01T10:00:10Z"},
+		{"TZ=Asia/Kolkata @aligned 1h", "2024-05-01T10:07:00Z", "2024-05-01T10:30:00Z"},     // +05:30
+		{"TZ=America/New_York @aligned 1h", "2024-03-10T06:30:00Z", "2024-03-10T07:00:00Z"}, // 01:00 EST to 03:00 EDT
+		{"TZ=America/New_York @aligned 1h", "2024-11-03T05:30:00Z", "2024-11-03T06:00:00Z"}, // the first 01:00
+		{"TZ=America/New_York @aligned 1h", "2024-11-03T06:00:00Z", "2024-11-03T07:00:00Z"}, // the second 01:00
+	}
+	for _, test := range tests {
+		t.Run(test.expr+" from "+test.from, func(t *testing.T) {
+			schedule, err := parseAligned(test.expr)
+			if err != nil || schedule == nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			from, _ := time.Parse(time.RFC3339, test.from)
+			if got := schedule.Next(from).UTC().Format(time.RFC3339); got != test.want {
+				t.Errorf("Next() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseAligned(t *testing.T) {
+	tests := []struct {
+		expr    string
+		aligned bool
+		wantErr bool
+	}{
+		{"@every 15m", false, false},
+		{"*/15 * * * *", false, false},
+		{"@aligned 15m", true, false},
+		{"@aligned 1h+59m", true, false},
+		{"@aligned", true, true},
+		{"@aligned 15", true, true},
+		{"@aligned 500ms", true, true},
+		{"@aligned 25h", true, true},
+		{"@aligned 1h+1h", true, true},
+		{"@aligned 1h+-5m", true, true},
+		{"@aligned 1h+5", true, true},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			schedule, err := parseAligned(test.expr)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error = %v, want error %v", err, test.wantErr)
+			}
+			if !test.wantErr && (schedule != nil) != test.aligned {
+				t.Errorf("aligned = %v, want %v", schedule != nil, test.aligned)
+			}
+		})
+	}
+}
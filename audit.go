@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+)
+
+const (
+	auditSourceSignal = "signal"
+	auditSourceAPI    = "api"
+	auditSourceCLI    = "cli"
+)
+
+type auditRecord struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	Actor  string    `json:"actor"`  // who
+	Source string    `json:"source"` // [signal, api, cli]
+	Job    string    `json:"job,omitempty"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// auditLog appends the operational actions to a file, one json per line.
+// The records are written to the default logger if the path is empty.
+type auditLog struct {
+	mu     sync.Mutex
+	file   *os.File
+	logger *logger
+}
+
+func newAuditLog(path string, defaultLogger *logger) (*auditLog, error) {
+	a := &auditLog{logger: defaultLogger}
+	if path == "" {
+		return a, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+	if err != nil {
+		return nil, err
+	}
+	a.file = f
+	return a, nil
+}
+
+func (a *auditLog) record(action, actor, source, job, detail string) {
+	r := auditRecord{Time: time.Now(), Action: action, Actor: actor, Source: source, Job: job, Detail: detail}
+	if a.file == nil {
+		a.logger.Info("audit", "action", r.Action, "actor", r.Actor, "source", r.Source, "job", r.Job, "detail", r.Detail)
+		return
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err = a.file.Write(append(data, '\n')); err != nil {
+		a.logger.Error(err, "write audit log fail", "action", action)
+	}
+}
+
+func (a *auditLog) close() {
+	if a.file != nil {
+		_ = a.file.Close()
+	}
+}
+
+// currentUser is the actor of the local actions, like signals and CLI
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return "unknown"
+}
+
+func (t *Task) SetAuditLog(path string) error {
+	a, err := newAuditLog(path, t.logger)
+	if err != nil {
+		return fmt.Errorf("open audit log error: %w", err)
+	}
+	t.audit = a
+	return nil
+}
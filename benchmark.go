@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// the config file of the synthetic jobs, the shell files are in the temporary directory
+var benchmarkConfigFile = filepath.Join(os.TempDir(), "benchmark")
+
+// benchmarkOptions are the options of --benchmark-jobs, which schedules the synthetic jobs for a while and reports
+type benchmarkOptions struct {
+	Jobs     int
+	Duration time.Duration
+	Schedule string
+	Command  string
+}
+
+// benchmark measures the scheduling latency of the firings, the concurrent runs and the memory
+type benchmark struct {
+	options   benchmarkOptions
+	startedAt time.Time
+
+	mu        sync.Mutex
+	latencies []time.Duration
+
+	running    int64
+	peak       int64
+	memoryPeak uint64 // bytes obtained from the os
+	heapPeak   uint64
+	goroutines int
+}
+
+// LoadBenchmark adds the synthetic jobs of the benchmark, named benchmark-<n>
+func (t *Task) LoadBenchmark(options benchmarkOptions) error {
+	if options.Jobs <= 0 {
+		return nil
+	}
+	if options.Duration <= 0 {
+		return fmt.Errorf("--benchmark-duration must be positive")
+	}
+
+	t.benchmark = &benchmark{options: options}
+	jobs := make([]*job, options.Jobs)
+	for i := range jobs {
+		jobs[i] = &job{Name: fmt.Sprintf("benchmark-%d", i+1), Schedule: options.Schedule, Command: options.Command}
+	}
+	return t.AddJob(benchmarkConfigFile, jobs...)
+}
+
+// measure wraps the job of the schedule, the latency is the delay of the run from the firing time of the schedule
+func (b *benchmark) measure(schedule cron.Schedule) cron.JobWrapper {
+	return func(next cron.Job) cron.Job {
+		var firing time.Time
+		return cron.FuncJob(func() {
+			now := time.Now()
+			b.mu.Lock()
+			if firing.IsZero() {
+				firing = b.startedAt
+			}
+			// the latest firing before now, cron runs once for the firings missed
+			for at := schedule.Next(firing); !at.After(now) && !at.IsZero(); at = schedule.Next(at) {
+				firing = at
+			}
+			b.latencies = append(b.latencies, now.Sub(firing))
+			b.mu.Unlock()
+
+			running := atomic.AddInt64(&b.running, 1)
+			defer atomic.AddInt64(&b.running, -1)
+			for peak := atomic.LoadInt64(&b.peak); running > peak && !atomic.CompareAndSwapInt64(&b.peak, peak, running); {
+				peak = atomic.LoadInt64(&b.peak)
+			}
+			next.Run()
+		})
+	}
+}
+
+// startBenchmark samples the memory until ctx is done, and stops the task after the duration
+func (t *Task) startBenchmark(ctx context.Context) {
+	b := t.benchmark
+	t.logger.Info("benchmark start", "jobs", b.options.Jobs, "schedule", b.options.Schedule, "duration", b.options.Duration.String())
+	time.AfterFunc(b.options.Duration, t.Stop)
+
+	go func() {
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			b.sample()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (b *benchmark) sample() {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if stats.Sys > b.memoryPeak {
+		b.memoryPeak = stats.Sys
+	}
+	if stats.HeapInuse > b.heapPeak {
+		b.heapPeak = stats.HeapInuse
+	}
+	if n := runtime.NumGoroutine(); n > b.goroutines {
+		b.goroutines = n
+	}
+}
+
+// ReportBenchmark logs the result of the benchmark after the task stopped
+func (t *Task) ReportBenchmark() {
+	b := t.benchmark
+	if b == nil {
+		return
+	}
+	b.sample()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	latencies := b.latencies
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	percentile := func(p int) string {
+		if len(latencies) <= 0 {
+			return "0s"
+		}
+		return latencies[(len(latencies)*p-1)/100].String()
+	}
+
+	t.logger.Info("benchmark finished",
+		"jobs", b.options.Jobs,
+		"duration", time.Since(b.startedAt).String(),
+		"runs", len(latencies),
+		"latency_p50", percentile(50),
+		"latency_p99", percentile(99),
+		"latency_max", percentile(100),
+		"concurrency_peak", atomic.LoadInt64(&b.peak),
+		"memory_peak_bytes", b.memoryPeak,
+		"heap_peak_bytes", b.heapPeak,
+		"goroutines_peak", b.goroutines,
+	)
+}
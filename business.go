@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"time"
+)
+
+const defaultWorkweek = "MON-FRI"
+
+const holidayLayout = "2006-01-02"
+
+// businessSchedule skips the firings on the days out of the workweek and on the holidays, in the location of the schedule
+type businessSchedule struct {
+	schedule cron.Schedule
+	workweek uint64          // the bits of the days of week
+	holidays map[string]bool // the dates like 2006-01-02
+	loc      *time.Location
+}
+
+func (job *job) businessSchedule(schedule cron.Schedule) (*businessSchedule, error) {
+	settings := job.task.loadingSettings()
+	workweek := job.Workweek
+	if workweek == "" {
+		workweek = settings.Workweek
+	}
+	if workweek == "" {
+		workweek = defaultWorkweek
+	}
+	spec, err := cronParser.Parse("0 0 0 * * " + workweek)
+	if err != nil {
+		return nil, fmt.Errorf("invalid workweek [%s]: %w", workweek, err)
+	}
+
+	holidays := map[string]bool{}
+	for _, date := range append(append([]string{}, settings.Holidays...), job.Holidays...) {
+		if _, err = time.Parse(holidayLayout, date); err != nil {
+			return nil, fmt.Errorf("invalid holiday [%s], must be like 2006-01-02", date)
+		}
+		holidays[date] = true
+	}
+
+	loc, _, err := scheduleFields(job.resolveAlias(job.Schedule))
+	if err != nil {
+		return nil, err
+	}
+	return &businessSchedule{schedule: schedule, workweek: spec.(*cron.SpecSchedule).Dow, holidays: holidays, loc: loc}, nil
+}
+
+func (s *businessSchedule) Next(t time.Time) time.Time {
+	// the cron parser gives up after 5 years too
+	for limit := t.AddDate(5, 0, 0); t.Before(limit); {
+		next := s.schedule.Next(t)
+		if next.IsZero() || s.businessDay(next) {
+			return next
+		}
+		// the first firing of the next day, instead of every firing of the day
+		y, m, d := next.In(s.location(next)).Date()
+		t = time.Date(y, m, d+1, 0, 0, 0, 0, s.location(next)).Add(-time.Nanosecond)
+	}
+	return time.Time{}
+}
+
+func (s *businessSchedule) location(t time.Time) *time.Location {
+	if s.loc == time.Local {
+		return t.Location()
+	}
+	return s.loc
+}
+
+func (s *businessSchedule) businessDay(t time.Time) bool {
+	t = t.In(s.location(t))
+	return s.workweek&(1<<uint(t.Weekday())) != 0 && !s.holidays[t.Format(holidayLayout)]
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBusinessNext(t *testing.T) {
+	// 2024-05-03 is friday
+	tests := []struct {
+		expr     string
+		workweek string
+		holidays []string
+		from     string
+		want     string
+	}{
+		{"TZ=UTC * * * * * *", "", nil, "2024-05-03T23:59:59Z", "2024-05-06T00:00:00Z"}, // every second of the weekend skipped
+		{"TZ=UTC 0 9 * * *", "", nil, "2024-05-03T10:00:00Z", "2024-05-06T09:00:00Z"},
+		{"TZ=UTC 0 9 * * *", "", nil, "2024-05-06T08:00:00Z", "2024-05-06T09:00:00Z"},
+		{"TZ=UTC 0 9 * * *", "SUN-THU", nil, "2024-05-02T10:00:00Z", "2024-05-05T09:00:00Z"},
+		{"TZ=UTC 0 9 * * *", "", []string{"2024-05-06", "2024-05-07"}, "2024-05-03T10:00:00Z", "2024-05-08T09:00:00Z"},
+		{"TZ=UTC */10 * * * * *", "", []string{"2024-12-25"}, "2024-12-24T23:59:55Z", "2024-12-26T00:00:00Z"},
+		{"TZ=Asia/Shanghai 0 9 * * *", "", nil, "2024-05-03T02:00:00Z", "2024-05-06T01:00:00Z"}, // the days in the location
+		{"TZ=Asia/Shanghai 0 30 23 * * *", "", nil, "2024-05-03T16:00:00Z", "2024-05-06T15:30:00Z"},
+	}
+	for _, test := range tests {
+		t.Run(test.expr+" from "+test.from, func(t *testing.T) {
+			j := newTestJob(test.expr)
+			j.BusinessDays = true
+			j.Workweek = test.workweek
+			j.Holidays = test.holidays
+			schedule, err := j.parseSchedule()
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			from, _ := time.Parse(time.RFC3339, test.from)
+			if got := schedule.Next(from).UTC().Format(time.RFC3339); got != test.want {
+				t.Errorf("Next() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseBusiness(t *testing.T) {
+	tests := []struct {
+		workweek string
+		holidays []string
+		wantErr  bool
+	}{
+		{"", nil, false},
+		{"SUN-THU", []string{"2024-12-25"}, false},
+		{"MON-SUN-FRI", nil, true},
+		{"", []string{"12/25/2024"}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.workweek, func(t *testing.T) {
+			j := newTestJob("0 9 * * *")
+			j.BusinessDays = true
+			j.Workweek = test.workweek
+			j.Holidays = test.holidays
+			if _, err := j.parseSchedule(); (err != nil) != test.wantErr {
+				t.Errorf("error = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	clockCheckInterval = time.Second
+	// the drift between the wall clock and the monotonic clock regarded as a jump
+	clockJumpThreshold = 10 * time.Second
+	maxMissedFirings   = 100
+)
+
+var catchUpPolicies = []string{"skip", "once", "all"}
+
+// startClockWatch detects the jumps of the wall clock until ctx is done, like suspend/resume and NTP steps.
+// The monotonic clock stops while suspended and is not stepped, so the drift between them is the jump.
+func (t *Task) startClockWatch(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(clockCheckInterval)
+		defer ticker.Stop()
+
+		last := time.Now()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				now := time.Now()
+				wall := now.Round(0).Sub(last.Round(0))
+				if drift := wall - now.Sub(last); drift > clockJumpThreshold || drift < -clockJumpThreshold {
+					t.clockJumped(last.Round(0), now.Round(0), drift)
+				}
+				last = now
+			}
+		}
+	}()
+}
+
+// clockJumped logs the missed firings, reschedules from now, and catches up per the policies of the jobs.
+// The firings are not missed if the clock jumped backward, the cron waits for the next ones computed before.
+func (t *Task) clockJumped(from, to time.Time, drift time.Duration) {
+	t.logger.Warn("clock jumped", "from", from, "to", to, "drift", drift.String())
+	t.events.publish(eventClockJump, "", map[string]any{"from": from, "to": to, "drift": drift.String()})
+	if drift < 0 {
+		return
+	}
+
+	t.jobsMu.RLock()
+	jobs := append([]*job{}, t.Jobs...)
+	t.jobsMu.RUnlock()
+
+	missed := map[*job][]time.Time{}
+	for _, j := range jobs {
+		entry := t.Cron.Entry(j.id)
+		if entry.Schedule == nil {
+			continue
+		}
+		for next := entry.Schedule.Next(from); !next.IsZero() && !next.After(to) && len(missed[j]) < maxMissedFirings; next = entry.Schedule.Next(next) {
+			missed[j] = append(missed[j], next)
+		}
+		if len(missed[j]) > 0 {
+			j.log().Warn("missed firings", "name", j.Name, "count", len(missed[j]), "first", missed[j][0], "last", missed[j][len(missed[j])-1], "catch_up", j.catchUp())
+		}
+	}
+
+	// the overdue firings would run once when the timer of the cron fires late, drop them by restarting the cron.
+	// halted is set by Stop under the same lock, the cron is never started again once stopping
+	t.reloadMu.Lock()
+	scheduling := t.isLeader() && !t.isDraining() && atomic.LoadInt32(&t.halted) == 0
+	t.Cron.Stop()
+	if scheduling {
+		t.Cron.Start()
+	}
+	t.reloadMu.Unlock()
+
+	if !scheduling {
+		return
+	}
+	for j, firings := range missed {
+		runs := 0
+		switch j.catchUp() {
+		case "once":
+			runs = 1
+		case "all":
+			runs = len(firings)
+		}
+		if runs <= 0 {
+			continue
+		}
+
+		wrapped := t.Cron.Entry(j.id).WrappedJob
+		if wrapped == nil {
+			continue
+		}
+		j.log().Info("catch up", "name", j.Name, "runs", runs)
+		go func() {
+			for i := 0; i < runs; i++ {
+				wrapped.Run()
+			}
+		}()
+	}
+}
+
+func (job *job) catchUp() string {
+	if job.CatchUp == "" {
+		return "skip"
+	}
+	return job.CatchUp
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// composeTarget is the service of a compose project, resolved to the running containers on every run
+type composeTarget struct {
+	Project string `json:"project" yaml:"project"` // the name of compose project, the directory of docker-compose.yml by default
+	Service string `json:"service" yaml:"service"`
+	All     bool   `json:"all" yaml:"all"`   // run in all containers of the scaled service, the oldest one by default
+	User    string `json:"user" yaml:"user"` // the user in the containers
+}
+
+func (c composeTarget) validate() error {
+	if c.Project == "" || c.Service == "" {
+		return fmt.Errorf("compose.project and compose.service required")
+	}
+	return nil
+}
+
+// composeContainers returns the ids of the running containers of the service, the oldest first
+func (t *Task) composeContainers(target composeTarget) ([]string, error) {
+	out, err := t.docker("ps", "--quiet", "--no-trunc",
+		"--filter", "status=running",
+		"--filter", "label=com.docker.compose.project="+target.Project,
+		"--filter", "label=com.docker.compose.service="+target.Service,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// docker ps lists the newest first
+	ids := strings.Fields(out)
+	for i, j := 0, len(ids)-1; i < j; i, j = i+1, j-1 {
+		ids[i], ids[j] = ids[j], ids[i]
+	}
+	if len(ids) <= 0 {
+		return nil, fmt.Errorf("no running containers of compose service %s/%s", target.Project, target.Service)
+	}
+	return ids, nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const consoleMaxRunning = 10 // the lines of running jobs in the table, the others are counted
+
+// isTerminal returns true if the file is a terminal, not a pipe or a file
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+// colorEnabled returns true if the console supports colors, disabled by NO_COLOR like https://no-color.org
+func colorEnabled() bool {
+	return os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+}
+
+// consoleWriter writes the logs to the terminal, keeping a table of the running jobs below them
+type consoleWriter struct {
+	mu     sync.Mutex
+	out    io.Writer
+	status func() []runningJob
+	lines  int // the lines of the table drawn
+	stop   chan struct{}
+}
+
+func newConsoleWriter(out io.Writer) *consoleWriter {
+	return &consoleWriter{out: out, stop: make(chan struct{})}
+}
+
+func (c *consoleWriter) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.erase()
+	n, err := c.out.Write(p)
+	c.draw()
+	return n, err
+}
+
+func (c *consoleWriter) Sync() error {
+	return nil
+}
+
+// watch redraws the table of the jobs every second, for the elapsed time
+func (c *consoleWriter) watch(status func() []runningJob) {
+	c.mu.Lock()
+	c.status = status
+	c.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-ticker.C:
+			}
+			c.mu.Lock()
+			c.erase()
+			c.draw()
+			c.mu.Unlock()
+		}
+	}()
+}
+
+// close erases the table, the logs are written without it after closed
+func (c *consoleWriter) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.status == nil {
+		return
+	}
+	close(c.stop)
+	c.erase()
+	c.status = nil
+}
+
+func (c *consoleWriter) erase() {
+	if c.lines > 0 {
+		_, _ = fmt.Fprintf(c.out, "\x1b[%dA\x1b[J", c.lines) // up to the first line of the table, clear to the end
+		c.lines = 0
+	}
+}
+
+func (c *consoleWriter) draw() {
+	if c.status == nil {
+		return
+	}
+	runs := c.status()
+	if len(runs) <= 0 {
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "── running jobs: %d ──\n", len(runs))
+	for i, run := range runs {
+		if i >= consoleMaxRunning {
+			fmt.Fprintf(&b, "   ... and %d more\n", len(runs)-i)
+			break
+		}
+		fmt.Fprintf(&b, "   %-40s %s\n", truncateText(run.Name, 40), time.Since(run.StartedAt).Truncate(time.Second))
+	}
+	table := b.String()
+	c.lines = strings.Count(table, "\n")
+	_, _ = io.WriteString(c.out, table)
+}
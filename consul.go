@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	consulMinTTL = 10 * time.Second
+	consulMaxTTL = 24 * time.Hour
+)
+
+type consulSettings struct {
+	Addr  string `json:"addr" yaml:"addr"` // like http://127.0.0.1:8500
+	Token string `json:"token" yaml:"token"`
+}
+
+// consulLocker locks by acquiring the key with a session, which is renewed while the lock is held.
+// The session is destroyed on unlock, after keep if keep > 0, and the key is deleted by the behavior of the session.
+type consulLocker struct {
+	settings consulSettings
+	client   *http.Client
+
+	mu       sync.Mutex
+	sessions map[string]*consulSession // token => session
+}
+
+type consulSession struct {
+	id   string
+	stop chan struct{}
+}
+
+// errConsulNotFound is returned if the session to renew is invalidated
+var errConsulNotFound = errors.New("consul status code: 404")
+
+var consulLockers sync.Map // consulSettings => *consulLocker, which keeps the sessions between lock and unlock
+
+func newConsulLocker(settings consulSettings) *consulLocker {
+	l, _ := consulLockers.LoadOrStore(settings, &consulLocker{
+		settings: settings,
+		client:   &http.Client{Timeout: 5 * time.Second},
+		sessions: map[string]*consulSession{},
+	})
+	return l.(*consulLocker)
+}
+
+func (c *consulLocker) String() string {
+	return "consul " + c.settings.Addr
+}
+
+func (c *consulLocker) put(path string, body []byte, response any) error {
+	req, err := http.NewRequest(http.MethodPut, strings.TrimRight(c.settings.Addr, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if c.settings.Token != "" {
+		req.Header.Set("X-Consul-Token", c.settings.Token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return errConsulNotFound
+	} else if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("consul status code: %d", resp.StatusCode)
+	}
+	if response == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(response)
+}
+
+func (c *consulLocker) lock(key, token string, ttl time.Duration) (bool, error) {
+	if ttl < consulMinTTL {
+		ttl = consulMinTTL
+	} else if ttl > consulMaxTTL {
+		ttl = consulMaxTTL
+	}
+
+	body, _ := json.Marshal(map[string]string{
+		"Name":      "cron-cli " + key,
+		"TTL":       fmt.Sprintf("%ds", int64(ttl.Seconds())),
+		"Behavior":  "delete",
+		"LockDelay": "0s",
+	})
+	var created struct {
+		ID string `json:"ID"`
+	}
+	if err := c.put("/v1/session/create", body, &created); err != nil {
+		return false, err
+	}
+
+	var acquired bool
+	if err := c.put("/v1/kv/"+strings.TrimLeft(key, "/")+"?acquire="+url.QueryEscape(created.ID), []byte(token), &acquired); err != nil || !acquired {
+		_ = c.put("/v1/session/destroy/"+created.ID, nil, nil)
+		return false, err
+	}
+
+	session := &consulSession{id: created.ID, stop: make(chan struct{})}
+	c.mu.Lock()
+	c.sessions[token] = session
+	c.mu.Unlock()
+	go c.renew(session, ttl)
+	return true, nil
+}
+
+// renew renews the session every half ttl until unlocked, or the session is invalidated
+func (c *consulLocker) renew(session *consulSession, ttl time.Duration) {
+	ticker := time.NewTicker(ttl / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-session.stop:
+			return
+		case <-ticker.C:
+		}
+		// retried at the next tick if consul is unavailable, the session expires after ttl anyway
+		if err := c.put("/v1/session/renew/"+session.id, nil, nil); errors.Is(err, errConsulNotFound) {
+			return
+		}
+	}
+}
+
+// unlock destroys the session after keep, the key is deleted by the behavior of the session
+func (c *consulLocker) unlock(key, token string, keep time.Duration) error {
+	c.mu.Lock()
+	session, ok := c.sessions[token]
+	delete(c.sessions, token)
+	c.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	close(session.stop)
+	if keep > 0 { // the session is at least consulMinTTL, it is kept until destroyed
+		time.AfterFunc(keep, func() { _ = c.put("/v1/session/destroy/"+session.id, nil, nil) })
+		return nil
+	}
+	return c.put("/v1/session/destroy/"+session.id, nil, nil)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// debouncer collapses the triggers within the quiet period into one run after the last trigger
+type debouncer struct {
+	mu      sync.Mutex
+	timer   *time.Timer
+	pending int
+}
+
+// trigger runs after the quiet period since the last trigger, returns the triggers pending
+func (d *debouncer) trigger(quiet time.Duration, run func()) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.pending++
+	var timer *time.Timer
+	timer = time.AfterFunc(quiet, func() {
+		d.mu.Lock()
+		// replaced by a later trigger after firing
+		if d.timer != timer {
+			d.mu.Unlock()
+			return
+		}
+		d.timer, d.pending = nil, 0
+		d.mu.Unlock()
+		run()
+	})
+	d.timer = timer
+	return d.pending
+}
+
+// stop drops the pending triggers
+func (d *debouncer) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer, d.pending = nil, 0
+	}
+}
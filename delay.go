@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"time"
+)
+
+// delayedSchedule has no firings before the time, the first firing of an interval schedule is at the time
+type delayedSchedule struct {
+	schedule  cron.Schedule
+	notBefore time.Time
+}
+
+func (job *job) delayedSchedule(schedule cron.Schedule) (*delayedSchedule, error) {
+	notBefore := time.Now().Add(time.Duration(job.InitialDelay))
+	if job.StartAt != "" {
+		startAt, err := time.Parse(time.RFC3339, job.StartAt)
+		if err != nil {
+			if startAt, err = time.ParseInLocation("2006-01-02 15:04:05", job.StartAt, time.Local); err != nil {
+				return nil, fmt.Errorf("invalid start_at [%s], must be like 2006-01-02T15:04:05Z07:00 or 2006-01-02 15:04:05", job.StartAt)
+			}
+		}
+		if startAt.After(notBefore) {
+			notBefore = startAt
+		}
+	}
+	return &delayedSchedule{schedule: schedule, notBefore: notBefore.Add(time.Second - 1).Truncate(time.Second)}, nil
+}
+
+func (s *delayedSchedule) Next(t time.Time) time.Time {
+	if !t.Before(s.notBefore) {
+		return s.schedule.Next(t)
+	}
+	if _, ok := s.schedule.(cron.ConstantDelaySchedule); ok {
+		return s.notBefore.In(t.Location())
+	}
+	return s.schedule.Next(s.notBefore.Add(-time.Second))
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// the containers labeled cron.enabled=true are discovered, with the jobs labeled like
+	//
+	//	cron.job.<name>.schedule=0 3 * * *
+	//	cron.job.<name>.command=pg_dump ...
+	//	cron.job.<name>.user=postgres
+	//
+	// or in the yaml file in the container, like cron.config=/etc/cron.yaml
+	discoveryLabel       = "cron.enabled=true"
+	discoveryJobPrefix   = "cron.job."
+	discoveryConfigLabel = "cron.config"
+	discoveryConfigFile  = "docker:" // + the container id
+	discoveryRetryPeriod = 5 * time.Second
+)
+
+// containerTarget is the container of docker-exec
+type containerTarget struct {
+	Name string   `json:"name" yaml:"name"` // the name or id of the container
+	User string   `json:"user" yaml:"user"` // the user in the container
+	Env  []string `json:"env" yaml:"env"`   // the environment variables in the container, like TZ=UTC
+}
+
+func (c containerTarget) validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("container.name required")
+	}
+	for _, env := range c.Env {
+		if key, _, ok := strings.Cut(env, "="); !ok || key == "" || strings.ContainsAny(key, " \t\n") {
+			return fmt.Errorf("container.env must be like KEY=VALUE, yours: %s", env)
+		}
+	}
+	return nil
+}
+
+// containerJob is a job of the yaml file in a container, only the fields executed in the container are allowed,
+// the others like the hooks, host_namespaces, log files and notifications run on the host
+type containerJob struct {
+	Name     string   `yaml:"name"`
+	Schedule string   `yaml:"schedule"`
+	Command  string   `yaml:"command"`
+	Env      []string `yaml:"env"`  // in the container
+	User     string   `yaml:"user"` // in the container
+}
+
+// discovering returns true if the jobs of the labeled containers or the sidecar container are followed
+func (t *Task) discovering() bool {
+	return (t.discovery || t.sidecar != "") && !t.testMode
+}
+
+// discoveryFilter returns the docker filter of the followed containers
+func (t *Task) discoveryFilter() string {
+	if t.sidecar != "" {
+		return "container=" + t.sidecar
+	}
+	return "label=" + discoveryLabel
+}
+
+// startDiscovery watches the docker events until quitting, the jobs of the labeled containers
+// are added when the containers start, and removed when they die
+func (t *Task) startDiscovery() {
+	if !t.discovering() {
+		return
+	}
+
+	go func() {
+		for {
+			if err := t.watchDockerEvents(); err != nil {
+				t.logger.Error(err, "watch docker events fail, retry later", "retry_period", discoveryRetryPeriod.String())
+			}
+
+			select {
+			case <-t.quitSignalCtx.Done():
+				return
+			case <-time.After(discoveryRetryPeriod):
+			}
+		}
+	}()
+}
+
+// watchDockerEvents subscribes to the events first, then syncs all labeled containers, so no starts are missed
+func (t *Task) watchDockerEvents() error {
+	died := "die"
+	if t.getSettings().Docker.engine() == "podman" {
+		died = "died"
+	}
+	args := t.dockerCommand("events",
+		"--filter", "type=container",
+		"--filter", "event=start",
+		"--filter", "event="+died,
+		"--filter", t.discoveryFilter(),
+		"--format", "{{json .}}",
+	)
+	cmd := exec.CommandContext(t.quitSignalCtx, args[0], args[1:]...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+	defer func() { _ = cmd.Wait() }()
+
+	t.syncContainers()
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		var e struct {
+			Status string `json:"status"`
+			ID     string `json:"id"`
+		}
+		if err = json.Unmarshal(scanner.Bytes(), &e); err != nil || e.ID == "" {
+			continue
+		}
+
+		switch e.Status {
+		case "start":
+			t.syncContainer(e.ID)
+		case died:
+			t.removeContainerJobs(e.ID)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("docker events exited")
+}
+
+// syncContainers syncs the jobs of all running labeled containers, called by watchDockerEvents after subscribing to the events
+func (t *Task) syncContainers() {
+	if !t.discovering() {
+		return
+	}
+
+	ids, err := t.runningContainers()
+	if err != nil {
+		t.logger.Error(err, "list docker containers fail")
+		return
+	}
+	running := map[string]bool{}
+	for _, id := range ids {
+		running[id] = true
+		t.syncContainer(id)
+	}
+
+	// the containers died while not watching
+	for _, j := range t.discoveredJobs("") {
+		if id := strings.TrimPrefix(j.configFile, discoveryConfigFile); !running[id] {
+			t.removeContainerJobs(id)
+		}
+	}
+}
+
+// runningContainers returns the ids of the running labeled containers, or of the sidecar container
+func (t *Task) runningContainers() ([]string, error) {
+	if t.sidecar == "" {
+		out, err := t.docker("ps", "--quiet", "--no-trunc", "--filter", "label="+discoveryLabel)
+		if err != nil {
+			return nil, err
+		}
+		return strings.Fields(out), nil
+	}
+
+	out, err := t.docker("ps", "--all", "--quiet", "--no-trunc", "--filter", "name=^/?"+t.sidecar+"$")
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, id := range strings.Fields(out) {
+		state, err := t.docker("inspect", "--format", "{{.State.Running}}", id)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(state) == "true" {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// syncContainer replaces the jobs of the container by its labels
+func (t *Task) syncContainer(id string) {
+	out, err := t.docker("inspect", "--format", "{{.Name}}\t{{json .Config.Labels}}", id)
+	if err != nil {
+		t.logger.Error(err, "inspect docker container fail", "container", id)
+		return
+	}
+	name, labels, _ := strings.Cut(strings.TrimSpace(out), "\t")
+	name = strings.TrimPrefix(name, "/")
+
+	var values map[string]string
+	if err = json.Unmarshal([]byte(labels), &values); err != nil {
+		t.logger.Error(err, "parse labels of docker container fail", "container", name)
+		return
+	}
+
+	jobs := labeledJobs(name, values)
+	if path := values[discoveryConfigLabel]; path != "" {
+		fileJobs, err := t.containerFileJobs(id, name, path)
+		if err != nil {
+			t.logger.Error(err, "read jobs of docker container fail", "container", name, "path", path)
+			return
+		}
+		jobs = append(jobs, fileJobs...)
+	}
+
+	// a reload swaps t.Jobs, waits for it
+	t.reloadMu.Lock()
+	defer t.reloadMu.Unlock()
+	t.removeDiscoveredJobs(id)
+	if len(jobs) <= 0 {
+		return
+	}
+	if err = t.AddJob(discoveryConfigFile+id, jobs...); err != nil {
+		t.logger.Error(err, "add jobs of docker container fail", "container", name)
+		t.removeDiscoveredJobs(id)
+		return
+	}
+	t.logger.Info("docker container discovered", "container", name, "jobs", len(jobs))
+}
+
+// labeledJobs parses the jobs of the labels, named <container>.<job>
+func labeledJobs(container string, labels map[string]string) []*job {
+	byName := map[string]*job{}
+	for key, value := range labels {
+		if !strings.HasPrefix(key, discoveryJobPrefix) {
+			continue
+		}
+		name, field, ok := strings.Cut(strings.TrimPrefix(key, discoveryJobPrefix), ".")
+		if !ok {
+			continue
+		}
+
+		j, ok := byName[name]
+		if !ok {
+			j = &job{Name: container + "." + name, Type: "docker-exec", Container: containerTarget{Name: container}}
+			byName[name] = j
+		}
+		switch field {
+		case "schedule":
+			j.Schedule = value
+		case "command":
+			j.Command = value
+		case "user":
+			j.Container.User = value
+		}
+	}
+
+	var jobs []*job
+	for _, j := range byName {
+		jobs = append(jobs, j)
+	}
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a].Name < jobs[b].Name })
+	return jobs
+}
+
+// containerFileJobs parses the schedules of the yaml file in the container, which are executed in the container.
+// The file is written by the container, so the fields other than containerJob are rejected.
+func (t *Task) containerFileJobs(id, container, path string) ([]*job, error) {
+	out, err := t.docker("exec", id, "cat", path)
+	if err != nil {
+		return nil, err
+	}
+	var actual struct {
+		Schedules []containerJob `yaml:"schedules"`
+	}
+	decoder := yaml.NewDecoder(strings.NewReader(out))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(&actual); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unmarshal yaml file \"%s\" error: %w", path, err)
+	}
+
+	jobs := make([]*job, 0, len(actual.Schedules))
+	for i, c := range actual.Schedules {
+		name := c.Name
+		if name == "" {
+			name = fmt.Sprintf("%s-%d", filepath.Base(path), i+1)
+		}
+		jobs = append(jobs, &job{
+			Name:      container + "." + name,
+			Schedule:  c.Schedule,
+			Command:   c.Command,
+			Type:      "docker-exec",
+			Container: containerTarget{Name: container, User: c.User, Env: c.Env},
+		})
+	}
+	return jobs, nil
+}
+
+// discoveredJobs returns the jobs of the container, or of all containers if id is empty
+func (t *Task) discoveredJobs(id string) []*job {
+	t.jobsMu.RLock()
+	defer t.jobsMu.RUnlock()
+
+	var jobs []*job
+	for _, j := range t.Jobs {
+		if j.configFile == discoveryConfigFile+id || (id == "" && strings.HasPrefix(j.configFile, discoveryConfigFile)) {
+			jobs = append(jobs, j)
+		}
+	}
+	return jobs
+}
+
+func (t *Task) removeContainerJobs(id string) {
+	t.reloadMu.Lock()
+	defer t.reloadMu.Unlock()
+	t.removeDiscoveredJobs(id)
+}
+
+// removeDiscoveredJobs removes the jobs of the container, the caller holds reloadMu
+func (t *Task) removeDiscoveredJobs(id string) {
+	if jobs := t.discoveredJobs(id); len(jobs) > 0 {
+		t.removeJobs(jobs)
+		t.logger.Info("jobs of docker container removed", "container", id, "jobs", len(jobs))
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const dockerTimeout = 10 * time.Second
+
+// containerEngines are the clis of the container features, podman is compatible with the docker cli
+var containerEngines = []string{"docker", "podman"}
+
+// dockerSettings is the connection of the docker cli, like DOCKER_HOST, DOCKER_TLS_VERIFY and DOCKER_CERT_PATH
+type dockerSettings struct {
+	Engine    string `json:"engine" yaml:"engine"`         // [docker, podman] docker by default, or podman if docker is not installed
+	Host      string `json:"host" yaml:"host"`             // like unix:///var/run/docker.sock, tcp://10.0.0.2:2376 or unix:///run/user/1000/podman/podman.sock, $DOCKER_HOST or $CONTAINER_HOST by default
+	TLSVerify bool   `json:"tls_verify" yaml:"tls_verify"` // verify the remote host by the ca.pem of cert_path
+	CertPath  string `json:"cert_path" yaml:"cert_path"`   // the directory of ca.pem, cert.pem and key.pem, the client certs are used if set
+}
+
+func (s dockerSettings) validate() error {
+	if s.Engine != "" && !containsString(containerEngines, s.Engine) {
+		return fmt.Errorf("docker.engine must be %v, yours: %s", containerEngines, s.Engine)
+	}
+	if s.Engine == "podman" && (s.TLSVerify || s.CertPath != "") {
+		return fmt.Errorf("docker.tls_verify and docker.cert_path are not supported by podman, use a ssh:// or unix:// host")
+	}
+	return nil
+}
+
+// engine returns the cli of the container features
+func (s dockerSettings) engine() string {
+	if s.Engine != "" {
+		return s.Engine
+	}
+	if _, err := exec.LookPath("docker"); err != nil {
+		if _, err = exec.LookPath("podman"); err == nil {
+			return "podman"
+		}
+	}
+	return "docker"
+}
+
+// dockerCommand returns the docker cli command with the connection options
+func (t *Task) dockerCommand(args ...string) []string {
+	settings := t.getSettings().Docker
+	command := []string{settings.engine()}
+	if command[0] == "podman" {
+		if settings.Host != "" {
+			command = append(command, "--remote", "--url", settings.Host)
+		}
+		return append(command, args...)
+	}
+
+	if settings.Host != "" {
+		command = append(command, "--host", settings.Host)
+	}
+	if settings.CertPath != "" {
+		command = append(command,
+			"--tlscacert", filepath.Join(settings.CertPath, "ca.pem"),
+			"--tlscert", filepath.Join(settings.CertPath, "cert.pem"),
+			"--tlskey", filepath.Join(settings.CertPath, "key.pem"),
+		)
+		if !settings.TLSVerify {
+			command = append(command, "--tls")
+		}
+	}
+	if settings.TLSVerify {
+		command = append(command, "--tlsverify")
+	}
+	return append(command, args...)
+}
+
+// docker runs the docker cli, returns the stdout
+func (t *Task) docker(args ...string) (string, error) {
+	command := t.dockerCommand(args...)
+	return runCLI(command[0], command[1:]...)
+}
+
+// runCLI runs the cli, like docker and kubectl, returns the stdout
+func runCLI(name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dockerTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %s", name, msg)
+		}
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return stdout.String(), nil
+}
+
+// dockerExec returns the command executing the shell command in the containers one by one
+func (t *Task) dockerExec(containers []string, user string, env []string, command string) []string {
+	args := t.dockerCommand("exec")
+	if user != "" {
+		args = append(args, "--user", user)
+	}
+	for _, e := range env {
+		args = append(args, "--env", e)
+	}
+	return execEach(args, containers, []string{"/bin/sh", "-c"}, command)
+}
+
+// execEach returns the command "<prefix> <target> <suffix> <command>" for every target one by one, fails if any failed
+func execEach(prefix, targets, suffix []string, command string) []string {
+	if len(targets) == 1 {
+		return append(append(append(append([]string{}, prefix...), targets[0]), suffix...), command)
+	}
+	script := `rc=0; cmd=$1; shift; for c; do ` + shellQuote(prefix...) + ` "$c" ` + shellQuote(suffix...) + ` "$cmd" || rc=$?; done; exit $rc`
+	return append([]string{"/bin/sh", "-c", script, "sh", command}, targets...)
+}
+
+// shellQuote quotes the arguments by single quotes for sh, separated by spaces
+func shellQuote(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
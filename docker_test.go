@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestExecEach(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sh")
+	}
+	// the arguments like --env are passed as is, not interpreted by the shell
+	prefix := []string{"printf", "%s|", "--env", "A=a b", "--env", "B=$(id)", "--user", "it's"}
+	tests := []struct {
+		targets []string
+		want    string
+	}{
+		{[]string{"c1"}, "--env|A=a b|--env|B=$(id)|--user|it's|c1|--|echo $HOME; exit 1|"},
+		{[]string{"c1", "c 2"}, "--env|A=a b|--env|B=$(id)|--user|it's|c1|--|echo $HOME; exit 1|" +
+			"--env|A=a b|--env|B=$(id)|--user|it's|c 2|--|echo $HOME; exit 1|"},
+	}
+	for _, test := range tests {
+		t.Run(strings.Join(test.targets, ","), func(t *testing.T) {
+			command := execEach(prefix, test.targets, []string{"--"}, "echo $HOME; exit 1")
+			output, err := exec.Command(command[0], command[1:]...).Output()
+			if err != nil {
+				t.Fatalf("run error: %v", err)
+			}
+			if string(output) != test.want {
+				t.Errorf("output = %q, want %q", output, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+)
+
+// isDraining returns true after Drain, no firings are scheduled anymore
+func (t *Task) isDraining() bool {
+	return atomic.LoadInt32(&t.draining) == 1
+}
+
+// Drain stops scheduling new firings, waits for the running jobs without any deadline, then quits.
+func (t *Task) Drain(actor, source string) error {
+	if t.testMode {
+		return fmt.Errorf("drain is unavailable in test mode")
+	}
+	if !atomic.CompareAndSwapInt32(&t.draining, 0, 1) {
+		return fmt.Errorf("already draining")
+	}
+
+	t.audit.record("drain", actor, source, "", "")
+	t.logger.Info("draining", "running", t.running.count(), "actor", actor, "source", source)
+	t.events.publish(eventDrain, "", map[string]any{"running": t.running.count()})
+	t.notifySystemd(sdStopping)
+
+	t.reloadMu.Lock()
+	stoppingCtx := t.Cron.Stop()
+	t.reloadMu.Unlock()
+	t.stopDebouncers()
+
+	go func() {
+		<-stoppingCtx.Done()
+		// the triggered runs are not waited by the cron
+		_ = t.running.wait(context.Background())
+		t.logger.Info("drained")
+		t.stopImpl(context.Background())
+	}()
+	return nil
+}
+
+// ListenDrainSignal drains when SIGUSR1 received, not available on windows
+func (t *Task) ListenDrainSignal() {
+	if len(drainSignals) <= 0 {
+		return
+	}
+	go func() {
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, drainSignals...)
+		for range ch {
+			if err := t.Drain("unknown", auditSourceSignal); err != nil {
+				t.logger.Error(err, "drain fail")
+			}
+		}
+	}()
+}
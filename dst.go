@@ -0,0 +1,102 @@
+package main
+
+import (
+	"github.com/robfig/cron/v3"
+	"time"
+)
+
+// the policies of the firings within the hour skipped or repeated by daylight-saving transitions.
+// The cron skips the firings within the skipped hour, and runs the ones within the repeated hour twice by default.
+var dstPolicies = []string{
+	"skip",   // skip the firings within the skipped hour, run the ones within the repeated hour once
+	"once",   // run the firings within the skipped hour once at the transition, the ones within the repeated hour once
+	"adjust", // run the firings within the skipped hour shifted by the transition (02:30 => 03:30), the ones within the repeated hour once
+}
+
+const dstWallLayout = "2006-01-02 15:04:05"
+
+// dstSchedule applies the dst policy to the spec schedule
+type dstSchedule struct {
+	spec   *cron.SpecSchedule
+	policy string
+	job    *job
+}
+
+func (s *dstSchedule) location(t time.Time) *time.Location {
+	if s.spec.Location == time.Local {
+		return t.Location()
+	}
+	return s.spec.Location
+}
+
+func (s *dstSchedule) Next(t time.Time) time.Time {
+	next := s.spec.Next(t)
+	if next.IsZero() {
+		return next
+	}
+
+	if s.policy != "skip" {
+		if skipped, gapped := s.skippedFiring(t, next); !gapped.IsZero() {
+			s.job.log().Info("dst skipped hour", "name", s.job.Name, "firing", skipped.Format(dstWallLayout), "policy", s.policy, "run_at", gapped)
+			return gapped
+		}
+	}
+
+	// the first firing of a wall time is kept, the ones repeated by the transition are skipped
+	for !next.IsZero() && s.repeated(next) {
+		s.job.log().Info("dst repeated hour", "name", s.job.Name, "firing", next, "policy", s.policy, "decision", "skip")
+		next = s.spec.Next(next)
+	}
+	return next
+}
+
+// repeated returns true if the wall time of the firing occurred before, after the clock was set back
+func (s *dstSchedule) repeated(firing time.Time) bool {
+	firing = firing.In(s.location(firing))
+	_, offset := firing.Zone()
+	_, before := firing.Add(-3 * time.Hour).Zone()
+	if before <= offset {
+		return false
+	}
+
+	earlier := firing.Add(-time.Duration(before-offset) * time.Second)
+	return earlier.Format(dstWallLayout) == firing.Format(dstWallLayout)
+}
+
+// skippedFiring finds the firing within the hour skipped by the transition between t and next,
+// returns the skipped wall time and when it runs per the policy, or zero if none
+func (s *dstSchedule) skippedFiring(t, next time.Time) (skipped time.Time, runAt time.Time) {
+	loc := s.location(t)
+	_, from := t.In(loc).Zone()
+	_, to := next.In(loc).Zone()
+	if to <= from {
+		return
+	}
+
+	// the first second of the new offset
+	lo, hi := t, next
+	for hi.Sub(lo) > time.Second {
+		mid := lo.Add(hi.Sub(lo) / 2)
+		if _, offset := mid.In(loc).Zone(); offset == from {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	transition := hi.Truncate(time.Second)
+	gap := time.Duration(to-from) * time.Second
+
+	// the wall times within the gap exist in the previous offset only
+	previous := *s.spec
+	previous.Location = time.FixedZone("", from)
+	firing := previous.Next(transition.Add(-time.Second))
+	if firing.IsZero() || !firing.Before(transition.Add(gap)) || !firing.Before(next) {
+		return
+	}
+
+	skipped = firing.In(previous.Location)
+	if s.policy == "once" {
+		return skipped, transition.In(t.Location())
+	}
+	return skipped, firing.In(t.Location())
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+// newTestJob returns a job of the schedule with a task logging nothing
+func newTestJob(schedule string) *job {
+	t := &Task{logger: &logger{zapLogger: zap.NewNop()}, states: &stateStore{states: map[string]*jobState{}}}
+	return &job{Name: "test", Schedule: schedule, task: t}
+}
+
+func TestDSTNext(t *testing.T) {
+	// America/New_York skips 02:00-03:00 on 2024-03-10, and repeats 01:00-02:00 on 2024-11-03
+	tests := []struct {
+		expr   string
+		policy string
+		from   string
+		want   string
+	}{
+		{"TZ=America/New_York 30 2 * * *", "", "2024-03-09T07:30:00Z", "2024-03-11T06:30:00Z"},
+		{"TZ=America/New_York 30 2 * * *", "skip", "2024-03-09T07:30:00Z", "2024-03-11T06:30:00Z"},
+		{"TZ=America/New_York 30 2 * * *", "once", "2024-03-09T07:30:00Z", "2024-03-10T07:00:00Z"},   // at the transition
+		{"TZ=America/New_York 30 2 * * *", "adjust", "2024-03-09T07:30:00Z", "2024-03-10T07:30:00Z"}, // 03:30
+		{"TZ=America/New_York 30 2 * * *", "adjust", "2024-03-10T07:30:00Z", "2024-03-11T06:30:00Z"},
+		{"TZ=America/New_York 30 3 * * *", "adjust", "2024-03-09T08:30:00Z", "2024-03-10T07:30:00Z"}, // not in the skipped hour
+
+		{"TZ=America/New_York 30 1 * * *", "", "2024-11-03T05:30:00Z", "2024-11-03T06:30:00Z"}, // twice by the cron
+		{"TZ=America/New_York 30 1 * * *", "skip", "2024-11-03T05:00:00Z", "2024-11-03T05:30:00Z"},
+		{"TZ=America/New_York 30 1 * * *", "skip", "2024-11-03T05:30:00Z", "2024-11-04T06:30:00Z"},
+		{"TZ=America/New_York 30 1 * * *", "once", "2024-11-03T05:30:00Z", "2024-11-04T06:30:00Z"},
+		{"TZ=America/New_York 0 * * * *", "skip", "2024-11-03T05:00:00Z", "2024-11-03T07:00:00Z"}, // 01:00 EST is repeated
+
+		{"TZ=Asia/Shanghai 30 2 * * *", "adjust", "2024-03-09T18:30:00Z", "2024-03-10T18:30:00Z"}, // no DST
+	}
+	for _, test := range tests {
+		t.Run(test.expr+" "+test.policy+" from "+test.from, func(t *testing.T) {
+			j := newTestJob(test.expr)
+			j.DSTPolicy = test.policy
+			schedule, err := j.parseSchedule()
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			from, _ := time.Parse(time.RFC3339, test.from)
+			if got := schedule.Next(from).UTC().Format(time.RFC3339); got != test.want {
+				t.Errorf("Next() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"time"
+)
+
+// duration accepts a string of time.ParseDuration like "1h30m", or an integer of milliseconds like timeout
+type duration time.Duration
+
+func parseDuration(s string) (duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration \"%s\": %w", s, err)
+	}
+	return duration(d), nil
+}
+
+func (d *duration) UnmarshalYAML(value *yaml.Node) error {
+	var ms int64
+	if err := value.Decode(&ms); err == nil {
+		*d = duration(time.Duration(ms) * time.Millisecond)
+		return nil
+	}
+
+	var s string
+	if err := value.Decode(&s); err != nil {
+		return err
+	}
+	parsed, err := parseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var ms int64
+	if err := json.Unmarshal(data, &ms); err == nil {
+		*d = duration(time.Duration(ms) * time.Millisecond)
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := parseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d duration) MarshalYAML() (any, error) {
+	return time.Duration(d).String(), nil
+}
+
+func (d duration) String() string {
+	return time.Duration(d).String()
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type etcdSettings struct {
+	Endpoints []string `json:"endpoints" yaml:"endpoints"` // like http://127.0.0.1:2379
+	Username  string   `json:"username" yaml:"username"`
+	Password  string   `json:"password" yaml:"password"`
+}
+
+// etcdLocker locks by a transaction putting the key with a lease if the key does not exist, via the json gateway of etcd v3
+type etcdLocker struct {
+	settings etcdSettings
+	client   *http.Client
+}
+
+func newEtcdLocker(settings etcdSettings) *etcdLocker {
+	return &etcdLocker{settings: settings, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func (e *etcdLocker) String() string {
+	return "etcd " + strings.Join(e.settings.Endpoints, ",")
+}
+
+func b64(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+// call posts to the endpoints in order, until one of them responds
+func (e *etcdLocker) call(path string, token string, request, response any) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for _, endpoint := range e.settings.Endpoints {
+		req, err := http.NewRequest(http.MethodPost, strings.TrimRight(endpoint, "/")+path, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+
+		resp, err := e.client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		err = func() error {
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("etcd status code: %d", resp.StatusCode)
+			}
+			return json.NewDecoder(resp.Body).Decode(response)
+		}()
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("etcd.endpoints required")
+	}
+	return lastErr
+}
+
+func (e *etcdLocker) authenticate() (string, error) {
+	if e.settings.Username == "" {
+		return "", nil
+	}
+	var resp struct {
+		Token string `json:"token"`
+	}
+	err := e.call("/v3/auth/authenticate", "", map[string]string{"name": e.settings.Username, "password": e.settings.Password}, &resp)
+	return resp.Token, err
+}
+
+func (e *etcdLocker) grant(token string, ttl time.Duration) (string, error) {
+	var resp struct {
+		ID string `json:"ID"`
+	}
+	seconds := int64(math.Ceil(ttl.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	if err := e.call("/v3/lease/grant", token, map[string]int64{"TTL": seconds}, &resp); err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+// revoke revokes the lease not attached to the key, instead of leaving it until the ttl
+func (e *etcdLocker) revoke(token, lease string) {
+	var resp struct{}
+	_ = e.call("/v3/lease/revoke", token, map[string]string{"ID": lease}, &resp)
+}
+
+// txnWithLease runs the txn putting the key with the lease, which is revoked if the txn fails or errors
+func (e *etcdLocker) txnWithLease(token, lease string, compare, success map[string]any) (bool, error) {
+	succeeded, err := e.txn(token, compare, success)
+	if err != nil || !succeeded {
+		e.revoke(token, lease)
+	}
+	return succeeded, err
+}
+
+func (e *etcdLocker) txn(token string, compare, success map[string]any) (bool, error) {
+	var resp struct {
+		Succeeded bool `json:"succeeded"`
+	}
+	err := e.call("/v3/kv/txn", token, map[string]any{
+		"compare": []any{compare},
+		"success": []any{success},
+	}, &resp)
+	return resp.Succeeded, err
+}
+
+func (e *etcdLocker) lock(key, value string, ttl time.Duration) (bool, error) {
+	token, err := e.authenticate()
+	if err != nil {
+		return false, err
+	}
+	lease, err := e.grant(token, ttl)
+	if err != nil {
+		return false, err
+	}
+
+	// the key does not exist if its create_revision is 0
+	return e.txnWithLease(token, lease,
+		map[string]any{"key": b64(key), "target": "CREATE", "create_revision": "0", "result": "EQUAL"},
+		map[string]any{"request_put": map[string]any{"key": b64(key), "value": b64(value), "lease": lease}},
+	)
+}
+
+func (e *etcdLocker) unlock(key, value string, keep time.Duration) error {
+	token, err := e.authenticate()
+	if err != nil {
+		return err
+	}
+
+	compare := map[string]any{"key": b64(key), "target": "VALUE", "value": b64(value), "result": "EQUAL"}
+	if keep > 0 { // put the key again with a shorter lease
+		lease, err := e.grant(token, keep)
+		if err != nil {
+			return err
+		}
+		_, err = e.txnWithLease(token, lease, compare, map[string]any{"request_put": map[string]any{"key": b64(key), "value": b64(value), "lease": lease}})
+		return err
+	}
+
+	_, err = e.txn(token, compare, map[string]any{"request_delete_range": map[string]any{"key": b64(key)}})
+	return err
+}
+
+// renew puts the key again with a new lease if the key is still held by the value
+func (e *etcdLocker) renew(key, value string, ttl time.Duration) (bool, error) {
+	token, err := e.authenticate()
+	if err != nil {
+		return false, err
+	}
+	lease, err := e.grant(token, ttl)
+	if err != nil {
+		return false, err
+	}
+
+	return e.txnWithLease(token, lease,
+		map[string]any{"key": b64(key), "target": "VALUE", "value": b64(value), "result": "EQUAL"},
+		map[string]any{"request_put": map[string]any{"key": b64(key), "value": b64(value), "lease": lease}},
+	)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	eventJobStarted   = "job-started"
+	eventJobFinished  = "job-finished"
+	eventJobSkipped   = "job-skipped"
+	eventJobRecovered = "job-recovered"
+	eventJobEnabled   = "job-enabled"
+	eventReload       = "reload"
+	eventLeader       = "leader"
+	eventCircuitOpen  = "circuit-open"
+	eventBudget       = "budget-exceeded"
+	eventDrain        = "drain"
+	eventPause        = "pause"
+	eventResume       = "resume"
+	eventClockJump    = "clock-jump"
+)
+
+type event struct {
+	Type string         `json:"type"`
+	Time time.Time      `json:"time"`
+	Job  string         `json:"job,omitempty"`
+	Data map[string]any `json:"data,omitempty"`
+}
+
+// eventBus fans out events to all subscribers, slow subscribers lose events instead of blocking the jobs.
+type eventBus struct {
+	mu          sync.Mutex
+	subscribers map[chan event]struct{}
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subscribers: map[chan event]struct{}{}}
+}
+
+func (b *eventBus) publish(typ, jobName string, data map[string]any) {
+	e := event{Type: typ, Time: time.Now(), Job: jobName, Data: data}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+func (b *eventBus) subscribe() (<-chan event, func()) {
+	ch := make(chan event, 64)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.subscribers, ch)
+		b.mu.Unlock()
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"time"
+)
+
+// the firings (or the excepted minutes) skipped by except in a row, the schedule gives up after that
+const maxExceptedFirings = 100000
+
+// exceptSchedule skips the firings of the schedule matching any of the except expressions
+type exceptSchedule struct {
+	schedule cron.Schedule
+	excepts  []exceptExpr
+	job      *job
+}
+
+// exceptExpr matches the times of the cron expression, to the minute if the expression has no seconds
+type exceptExpr struct {
+	schedule cron.Schedule
+	minutely bool
+}
+
+func (job *job) exceptSchedule(schedule cron.Schedule) (*exceptSchedule, error) {
+	s := &exceptSchedule{schedule: schedule, job: job}
+	for _, expr := range job.Except {
+		expr = job.resolveAlias(expr)
+		except, err := parseQuartz(expr)
+		if err == nil && except == nil {
+			except, err = cronParser.Parse(expr)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid except [%s]: %w", expr, err)
+		}
+		_, fields, _ := scheduleFields(expr)
+		s.excepts = append(s.excepts, exceptExpr{schedule: except, minutely: len(fields) == 5})
+	}
+	return s, nil
+}
+
+func (s *exceptSchedule) Next(t time.Time) time.Time {
+	next := s.schedule.Next(t)
+	for i := 0; i < maxExceptedFirings && !next.IsZero(); i++ {
+		until, excepted := s.excepted(next)
+		if !excepted {
+			return next
+		}
+		// the other firings within the excepted minute are skipped at once
+		next = s.schedule.Next(until)
+	}
+	if next.IsZero() {
+		return next
+	} else if _, excepted := s.excepted(next); !excepted {
+		return next
+	}
+	s.job.log().Warn("except skipped too many firings, no more firings", "name", s.job.Name, "skipped", maxExceptedFirings, "until", next)
+	return time.Time{}
+}
+
+// excepted returns true if any except matches the firing, until is the last time matched by the except
+func (s *exceptSchedule) excepted(t time.Time) (until time.Time, excepted bool) {
+	for _, except := range s.excepts {
+		at := t.Truncate(time.Second)
+		if except.minutely {
+			at = t.Truncate(time.Minute)
+		}
+		if !except.schedule.Next(at.Add(-time.Second)).Equal(at) {
+			continue
+		}
+		if excepted = true; except.minutely {
+			at = at.Add(time.Minute - time.Nanosecond)
+		}
+		if at.After(until) {
+			until = at
+		}
+	}
+	if excepted && until.Before(t) {
+		until = t
+	}
+	return until, excepted
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExceptNext(t *testing.T) {
+	tests := []struct {
+		expr   string
+		except []string
+		from   string
+		want   string // empty if every firing is excepted
+	}{
+		{"TZ=UTC 0 * * * *", []string{"TZ=UTC * 0-5 * * *"}, "2024-05-01T23:30:00Z", "2024-05-02T06:00:00Z"},
+		{"TZ=UTC 0 * * * *", []string{"TZ=UTC * 0-5 * * *", "TZ=UTC * 6 * * *"}, "2024-05-01T23:30:00Z", "2024-05-02T07:00:00Z"},
+		{"TZ=UTC */20 * * * * *", []string{"TZ=UTC 10 * * * *"}, "2024-05-01T00:09:50Z", "2024-05-01T00:11:00Z"},        // the whole minute
+		{"TZ=UTC */30 * * * * *", []string{"TZ=UTC 30 * * * * *"}, "2024-05-01T00:00:00Z", "2024-05-01T00:01:00Z"},      // to the second
+		{"TZ=UTC 0 9 * * *", []string{"TZ=UTC 0 0 L * *"}, "2024-04-29T10:00:00Z", "2024-04-30T09:00:00Z"},              // minutely, not at 09:00
+		{"TZ=UTC 0 9 * * *", []string{"TZ=UTC * * L * *"}, "2024-04-29T10:00:00Z", "2024-05-01T09:00:00Z"},              // the last day
+		{"TZ=UTC 0 9 * * *", []string{"TZ=Asia/Shanghai * 17 * * WED"}, "2024-05-01T00:00:00Z", "2024-05-02T09:00:00Z"}, // in another location
+		{"TZ=UTC 0 9 * * *", []string{"@weekend"}, "2024-05-03T10:00:00Z", "2024-05-06T09:00:00Z"},                      // the alias
+		{"TZ=UTC 0 0 1 * *", []string{"TZ=UTC * * 1 * *"}, "2024-05-01T00:00:00Z", ""},
+		{"TZ=UTC * * * * * *", []string{"TZ=UTC * * * * SAT,SUN"}, "2024-05-03T23:59:59Z", "2024-05-06T00:00:00Z"}, // a minute skipped at once
+		{"TZ=UTC * * * * * *", []string{"TZ=UTC * * * * * SAT,SUN"}, "2024-05-03T23:59:59Z", ""},                   // too many seconds skipped
+	}
+	for _, test := range tests {
+		t.Run(test.expr+" from "+test.from, func(t *testing.T) {
+			j := newTestJob(test.expr)
+			j.Except = test.except
+			j.task.settings.ScheduleAliases = map[string]string{"weekend": "TZ=UTC * * * * SAT,SUN"}
+			schedule, err := j.parseSchedule()
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			from, _ := time.Parse(time.RFC3339, test.from)
+			var got string
+			if next := schedule.Next(from); !next.IsZero() {
+				got = next.UTC().Format(time.RFC3339)
+			}
+			if got != test.want {
+				t.Errorf("Next() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseExcept(t *testing.T) {
+	tests := []struct {
+		except  []string
+		wantErr bool
+	}{
+		{[]string{"* 0-5 * * *"}, false},
+		{[]string{"* * * * 5L"}, false},
+		{[]string{"* 0-5 * * *", "* * 32 * *"}, true},
+		{[]string{"@unknown"}, true},
+		{[]string{"TZ=Nowhere/City * * * * *"}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.except[len(test.except)-1], func(t *testing.T) {
+			j := newTestJob("0 * * * *")
+			j.Except = test.except
+			if _, err := j.parseSchedule(); (err != nil) != test.wantErr {
+				t.Errorf("error = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
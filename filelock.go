@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fileLocker locks by flock on a file of the shared storage (NFS/EFS), the lock is released if the process dies.
+// The keeping deadline is written to the file on unlock, the others treat the file as locked until the deadline.
+type fileLocker struct {
+	dir string
+
+	mu    sync.Mutex
+	files map[string]*os.File // token => the locked file
+}
+
+var fileLockers sync.Map // dir => *fileLocker
+
+func newFileLocker(dir string) *fileLocker {
+	l, _ := fileLockers.LoadOrStore(dir, &fileLocker{dir: dir, files: map[string]*os.File{}})
+	return l.(*fileLocker)
+}
+
+func (f *fileLocker) String() string {
+	return "file " + f.dir
+}
+
+// path of the key, the key is used as is if it is an absolute path
+func (f *fileLocker) path(key string) string {
+	if filepath.IsAbs(key) {
+		return key
+	}
+	return filepath.Join(f.dir, strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(key)+".lock")
+}
+
+func (f *fileLocker) lock(key, token string, ttl time.Duration) (bool, error) {
+	path := f.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return false, err
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return false, err
+	}
+
+	ok, err := flock(file)
+	if err != nil || !ok {
+		_ = file.Close()
+		return false, err
+	}
+
+	// released by the others, but still kept
+	content, _ := os.ReadFile(path)
+	if fields := strings.Fields(string(content)); len(fields) == 2 {
+		if deadline, err := strconv.ParseInt(fields[1], 10, 64); err == nil && time.Now().UnixNano() < deadline {
+			_ = funlock(file)
+			_ = file.Close()
+			return false, nil
+		}
+	}
+
+	if err = writeLockFile(file, token, 0); err != nil {
+		_ = funlock(file)
+		_ = file.Close()
+		return false, err
+	}
+
+	f.mu.Lock()
+	f.files[token] = file
+	f.mu.Unlock()
+	return true, nil
+}
+
+// unlock writes the keeping deadline, then releases the flock
+func (f *fileLocker) unlock(key, token string, keep time.Duration) error {
+	f.mu.Lock()
+	file, ok := f.files[token]
+	delete(f.files, token)
+	f.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	defer file.Close()
+
+	var deadline int64
+	if keep > 0 {
+		deadline = time.Now().Add(keep).UnixNano()
+	}
+	if err := writeLockFile(file, token, deadline); err != nil {
+		_ = funlock(file)
+		return err
+	}
+	return funlock(file)
+}
+
+func writeLockFile(file *os.File, token string, deadline int64) error {
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	_, err := file.WriteAt([]byte(fmt.Sprintf("%s %d\n", token, deadline)), 0)
+	return err
+}
@@ -0,0 +1,16 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func flock(file *os.File) (bool, error) {
+	return false, fmt.Errorf("lock: file is not supported on windows")
+}
+
+func funlock(file *os.File) error {
+	return nil
+}
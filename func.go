@@ -1,8 +1,33 @@
 package main
 
+// truncateText keeps the first max characters, cut on the rune boundaries
 func truncateText(s string, max int) string {
 	if max >= len(s) {
 		return s
 	}
-	return s[:max] + "..."
+	for i := range s {
+		if max == 0 {
+			return s[:i] + "..."
+		}
+		max--
+	}
+	return s
+}
+
+func containsString(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(items []int, n int) bool {
+	for _, item := range items {
+		if item == n {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const healthCheckInterval = 2 * time.Second
+
+// unhealthyContainers returns the containers not healthy, the containers without health checks are healthy if running
+func (t *Task) unhealthyContainers(containers []string) ([]string, error) {
+	out, err := t.docker(append([]string{"inspect", "--format", "{{if .State.Health}}{{.State.Health.Status}}{{else}}{{.State.Status}}{{end}}"}, containers...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var unhealthy []string
+	for i, status := range strings.Split(strings.TrimSpace(out), "\n") {
+		if i < len(containers) && status != "healthy" && status != "running" {
+			unhealthy = append(unhealthy, containers[i]+" "+status)
+		}
+	}
+	return unhealthy, nil
+}
+
+// waitHealthy waits for the containers of requires_healthy until healthy_wait, returns the error if still not healthy
+func (job *job) waitHealthy(log *logger) error {
+	if len(job.RequiresHealthy) <= 0 {
+		return nil
+	}
+
+	deadline := time.Now().Add(time.Duration(job.HealthyWait))
+	for logged := false; ; logged = true {
+		unhealthy, err := job.task.unhealthyContainers(job.RequiresHealthy)
+		if err == nil && len(unhealthy) <= 0 {
+			return nil
+		} else if err == nil {
+			err = fmt.Errorf("not healthy: %s", strings.Join(unhealthy, ", "))
+		}
+
+		if !time.Now().Add(healthCheckInterval).Before(deadline) {
+			return err
+		}
+		if !logged {
+			log.Info("waiting for healthy", "name", job.Name, "containers", job.RequiresHealthy, "reason", err.Error(), "healthy_wait", job.HealthyWait.String())
+		}
+
+		select {
+		case <-job.task.quitSignalCtx.Done():
+			return err
+		case <-time.After(healthCheckInterval):
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+type heartbeatOptions struct {
+	Interval time.Duration
+	URL      string // GET the url on every beat
+	File     string // write the unix timestamp to the file on every beat
+}
+
+// startHeartbeat beats periodically until ctx is done.
+// A beat is skipped if the scheduler is wedged, so the external monitoring notices that the beats stopped.
+func (t *Task) startHeartbeat(ctx context.Context) {
+	if t.heartbeat.Interval <= 0 {
+		return
+	}
+
+	if t.heartbeat.File != "" {
+		if err := os.MkdirAll(filepath.Dir(t.heartbeat.File), os.ModePerm); err != nil {
+			t.logger.Error(err, "create heartbeat directory fail", "file", t.heartbeat.File)
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(t.heartbeat.Interval)
+		defer ticker.Stop()
+
+		t.beat()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				t.beat()
+			}
+		}
+	}()
+}
+
+// schedulerAlive checks the main loop of cron, Entries() blocks if the loop is wedged.
+func (t *Task) schedulerAlive(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		t.Cron.Entries()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (t *Task) beat() {
+	if !t.schedulerAlive(t.heartbeat.Interval / 2) {
+		t.logger.Error(fmt.Errorf("no response from the scheduler"), "heartbeat skipped")
+		return
+	}
+
+	now := time.Now()
+	t.metrics.set("cron_heartbeat_timestamp_seconds", float64(now.Unix()))
+
+	if t.heartbeat.File != "" {
+		if err := os.WriteFile(t.heartbeat.File, []byte(strconv.FormatInt(now.Unix(), 10)), 0o644); err != nil {
+			t.logger.Error(err, "write heartbeat file fail", "file", t.heartbeat.File)
+		}
+	}
+
+	if t.heartbeat.URL != "" {
+		go func() {
+			resp, err := pingClient.Get(t.heartbeat.URL)
+			if err != nil {
+				t.logger.Error(redactError(err), "heartbeat fail", "url", redactURL(t.heartbeat.URL))
+				return
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode >= 400 {
+				t.logger.Error(fmt.Errorf("status code: %d", resp.StatusCode), "heartbeat fail", "url", redactURL(t.heartbeat.URL))
+			}
+		}()
+	}
+}
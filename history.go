@@ -0,0 +1,82 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const defaultHistorySize = 100
+
+type runRecord struct {
+	RunID      string        `json:"run_id"`
+	StartedAt  time.Time     `json:"started_at"`
+	Duration   time.Duration `json:"duration"`
+	ExitCode   int           `json:"exit_code"`
+	UserTime   time.Duration `json:"user_time"`
+	SystemTime time.Duration `json:"system_time"`
+	MaxRSS     int64         `json:"max_rss"` // bytes
+	Error      string        `json:"error,omitempty"`
+}
+
+func (r runRecord) success() bool {
+	return r.ExitCode == 0 && r.Error == ""
+}
+
+// runHistory keeps the latest records of a job, the oldest record is evicted when full.
+type runHistory struct {
+	mu      sync.Mutex
+	size    int
+	records []runRecord
+}
+
+func newRunHistory(size int) *runHistory {
+	if size <= 0 {
+		size = defaultHistorySize
+	}
+	return &runHistory{size: size}
+}
+
+func (h *runHistory) add(record runRecord) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.records = append(h.records, record)
+	if len(h.records) > h.size {
+		h.records = h.records[len(h.records)-h.size:]
+	}
+}
+
+func (h *runHistory) list() []runRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	records := make([]runRecord, len(h.records))
+	copy(records, h.records)
+	return records
+}
+
+func (h *runHistory) last() (runRecord, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.records) <= 0 {
+		return runRecord{}, false
+	}
+	return h.records[len(h.records)-1], true
+}
+
+// newRunID generates a unique id for every execution
+func newRunID() string {
+	return randomHex(8)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// hookCommand builds the command of a hook, which is executed by the same shell as the command of job
+func (job *job) hookCommand(ctx context.Context, hook string) *exec.Cmd {
+	args := append(job.shell(), job.shellInline(hook)...)
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	setProcessGroup(cmd)
+	if !job.task.InDocker() {
+		cmd.Dir = job.WorkDirectory
+	}
+	return cmd
+}
+
+// saveOutputFile writes the output of the run to a temporary file for the hooks, returns the path seen by the hooks
+func (job *job) saveOutputFile(runID string, output *outputBuffer) (path string, remove func(), err error) {
+	path = filepath.Join(os.TempDir(), fmt.Sprintf("cron-output-%s.log", runID))
+	actual := path
+	if job.task.InDocker() { // the hooks run in the host
+		path = filepath.Join("/tmp", filepath.Base(path))
+		actual = filepath.Join(job.task.rootPathInDocker, path)
+	}
+
+	if err = os.WriteFile(actual, []byte(output.String()), 0o600); err != nil {
+		return "", nil, err
+	}
+	return path, func() { _ = os.Remove(actual) }, nil
+}
+
+// runHook executes the hook with CRON_JOB_NAME, CRON_RUN_ID in the environment,
+// and the result of the run if output is not nil (the hooks after the command):
+//
+//	CRON_EXIT_CODE, CRON_DURATION (seconds), CRON_OUTPUT_FILE
+func (job *job) runHook(log *logger, name, hook string, record runRecord, output *outputBuffer) error {
+	if hook == "" {
+		return nil
+	}
+
+	ctx := job.task.quitSignalCtx
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(job.Timeout)*time.Millisecond)
+		defer cancel()
+	}
+
+	var truncatedHook = log.command(hook)
+	cmd := job.hookCommand(ctx, hook)
+	cmd.Env = append(append(os.Environ(), job.Env...), "CRON_JOB_NAME="+job.Name, "CRON_RUN_ID="+record.RunID)
+
+	if output != nil {
+		outputFile, remove, err := job.saveOutputFile(record.RunID, output)
+		if err != nil {
+			log.Error(err, "save output file fail", "name", job.Name, "hook", name)
+		} else {
+			defer remove()
+		}
+		cmd.Env = append(cmd.Env,
+			"CRON_EXIT_CODE="+strconv.Itoa(record.ExitCode),
+			"CRON_DURATION="+strconv.FormatFloat(record.Duration.Seconds(), 'f', 3, 64),
+			"CRON_OUTPUT_FILE="+outputFile,
+		)
+	}
+	cmd.Stdout = log.stdout("hook", name, "command", truncatedHook, "id", job.id)
+	cmd.Stderr = log.stderr("hook", name, "command", truncatedHook, "id", job.id)
+
+	log.Info("executing hook", "name", job.Name, "hook", name, "command", truncatedHook)
+	if err := cmd.Run(); err != nil {
+		log.Error(err, "hook execution fail", "name", job.Name, "hook", name, "command", truncatedHook)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	pagerdutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+	opsgenieAPIURL     = "https://api.opsgenie.com"
+)
+
+// severities of jobs, the same as pagerduty
+var opsgeniePriorities = map[string]string{
+	"critical": "P1",
+	"error":    "P2",
+	"warning":  "P3",
+	"info":     "P5",
+}
+
+type pagerdutySettings struct {
+	RoutingKey string `json:"routing_key" yaml:"routing_key"` // the integration key of Events API v2
+}
+
+type opsgenieSettings struct {
+	APIKey string `json:"api_key" yaml:"api_key"`
+	APIURL string `json:"api_url" yaml:"api_url"` // https://api.opsgenie.com by default, or https://api.eu.opsgenie.com
+}
+
+var incidentClient = &http.Client{Timeout: 10 * time.Second}
+
+// incidentKey is the dedup key of the incidents of a job, so the recovery resolves the incident of the failure
+func incidentKey(n *notification) string {
+	return "cron/" + n.Hostname + "/" + n.Job
+}
+
+func incidentSummary(n *notification) string {
+	if n.Message != "" {
+		return n.Message
+	}
+	summary := fmt.Sprintf("cron job %s failed on %s, exit code: %d", n.Job, n.Hostname, n.ExitCode)
+	if n.Error != "" {
+		summary += ", error: " + n.Error
+	}
+	return summary
+}
+
+func postJSON(u string, headers map[string]string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, val := range headers {
+		req.Header.Set(k, val)
+	}
+	return doRequest(incidentClient, req)
+}
+
+// pagerdutyNotifier triggers an incident on failure, resolves it on recovery
+type pagerdutyNotifier struct {
+	settings pagerdutySettings
+	severity string
+}
+
+func (p pagerdutyNotifier) accepts(event string) bool {
+	return event == notifyFailure || event == notifyRecovery
+}
+
+func (p pagerdutyNotifier) retries() int {
+	return defaultWebhookRetries
+}
+
+func (p pagerdutyNotifier) String() string {
+	return "pagerduty"
+}
+
+func (p pagerdutyNotifier) send(n *notification) error {
+	event := map[string]any{
+		"routing_key":  p.settings.RoutingKey,
+		"dedup_key":    incidentKey(n),
+		"event_action": "resolve",
+	}
+	if n.Event == notifyFailure {
+		event["event_action"] = "trigger"
+		event["payload"] = map[string]any{
+			"summary":        incidentSummary(n),
+			"source":         n.Hostname,
+			"severity":       p.severity,
+			"component":      n.Job,
+			"custom_details": n,
+		}
+	}
+	return postJSON(pagerdutyEventsURL, nil, event)
+}
+
+// opsgenieNotifier creates an alert on failure, closes it on recovery
+type opsgenieNotifier struct {
+	settings opsgenieSettings
+	severity string
+}
+
+func (o opsgenieNotifier) accepts(event string) bool {
+	return event == notifyFailure || event == notifyRecovery
+}
+
+func (o opsgenieNotifier) retries() int {
+	return defaultWebhookRetries
+}
+
+func (o opsgenieNotifier) String() string {
+	return "opsgenie"
+}
+
+func (o opsgenieNotifier) send(n *notification) error {
+	apiURL := strings.TrimRight(o.settings.APIURL, "/")
+	if apiURL == "" {
+		apiURL = opsgenieAPIURL
+	}
+	headers := map[string]string{"Authorization": "GenieKey " + o.settings.APIKey}
+	alias := incidentKey(n)
+
+	if n.Event == notifyRecovery {
+		return postJSON(apiURL+"/v2/alerts/"+url.PathEscape(alias)+"/close?identifierType=alias", headers, map[string]string{
+			"source": n.Hostname,
+			"note":   "recovered, run id: " + n.RunID,
+		})
+	}
+
+	return postJSON(apiURL+"/v2/alerts", headers, map[string]any{
+		"message":     truncateText(incidentSummary(n), 120),
+		"alias":       alias,
+		"description": n.Output,
+		"priority":    opsgeniePriorities[o.severity],
+		"source":      n.Hostname,
+		"entity":      n.Job,
+		"details": map[string]string{
+			"schedule":  n.Schedule,
+			"run_id":    n.RunID,
+			"exit_code": fmt.Sprintf("%d", n.ExitCode),
+			"duration":  n.Duration,
+		},
+	})
+}
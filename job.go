@@ -2,93 +2,469 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"github.com/robfig/cron/v3"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+const defaultCircuitCooldown = 10 * time.Minute
+
+var jobTypes = []string{"shell", "docker-exec", "compose-exec", "kubectl-exec"}
+
 type job struct {
-	Schedule      string   `json:"schedule" yaml:"schedule"`
-	WorkDirectory string   `json:"work_directory" yaml:"work_directory"` // disabled in docker mode
-	Command       string   `json:"command" yaml:"command"`
-	Env           []string `json:"env" yaml:"env"`
-	Timeout       int64    `json:"timeout" yaml:"timeout"`
-	RunningMode   string   `json:"running_mode"` // [skip, delay, on-time(default)] if last job is running
-
-	StdoutLog string `json:"stdout_log" yaml:"stdout_log"`
-	StderrLog string `json:"stderr_log" yaml:"stderr_log"`
-	logger    *logger
-
-	id        cron.EntryID
-	task      *Task
-	shellFile string
+	Name            string   `json:"name" yaml:"name"`
+	Schedule        string   `json:"schedule" yaml:"schedule"`
+	Tags            []string `json:"tags" yaml:"tags"`                     // the labels of the job to filter by --tags, like [db, nightly]
+	WorkDirectory   string   `json:"work_directory" yaml:"work_directory"` // disabled in docker mode, unless host_namespaces set
+	Command         string   `json:"command" yaml:"command"`
+	Shell           string   `json:"shell" yaml:"shell"`                       // [sh, cmd, powershell, pwsh] the interpreter of the command, cmd by default on windows, sh by default on the others
+	ScriptStdin     bool     `json:"script_stdin" yaml:"script_stdin"`         // pipe the command to the shell via stdin (sh -s) instead of a temporary shell file on disk, not supported by cmd
+	CommandTemplate bool     `json:"command_template" yaml:"command_template"` // expand the command by text/template on every run, like backup-{{.Date "2006-01-02"}}.tar, see commandData
+	Env             []string `json:"env" yaml:"env"`
+	Timeout         int64    `json:"timeout" yaml:"timeout"`
+	StoppingTimeout duration `json:"stopping_timeout" yaml:"stopping_timeout"`     // the command is killed if still running after SIGTERM when quitting, the global stopping timeout by default
+	RunningMode     string   `json:"running_mode"`                                 // [skip, delay, on-time(default)] if last job is running
+	AnomalyFactor   float64  `json:"anomaly_factor" yaml:"anomaly_factor"`         // warn if a run takes longer than factor * median, 0 means 3, negative to disable
+	MinInterval     duration `json:"min_interval" yaml:"min_interval"`             // skip the firings (and triggers) within the interval since the last run started, like 10m
+	CatchUp         string   `json:"catch_up" yaml:"catch_up"`                     // [skip(default), once, all] the firings missed by a clock jump forward, like suspend/resume
+	DSTPolicy       string   `json:"dst_policy" yaml:"dst_policy"`                 // [skip, once, adjust] the firings within the hour skipped or repeated by daylight-saving transitions
+	InitialDelay    duration `json:"initial_delay" yaml:"initial_delay"`           // no firings within the delay since the job is loaded, the first firing of @every is at the end of it, like 5m
+	StartAt         string   `json:"start_at" yaml:"start_at"`                     // no firings before the time of RFC 3339 or "2006-01-02 15:04:05" in local time, like initial_delay
+	Except          []string `json:"except" yaml:"except"`                         // skip the firings matching any of the cron expressions, like ["* 0-5 * * *", "* * 1 * *"]
+	BusinessDays    bool     `json:"business_days_only" yaml:"business_days_only"` // skip the firings on the days out of the workweek and on the holidays
+	Workweek        string   `json:"workweek" yaml:"workweek"`                     // the days of week of business_days_only like the cron, the global workweek or MON-FRI by default, like SUN-THU
+	Holidays        []string `json:"holidays" yaml:"holidays"`                     // the dates skipped by business_days_only besides the global holidays, like [2024-12-25]
+
+	Type       string           `json:"type" yaml:"type"`             // [shell(default), docker-exec, compose-exec, kubectl-exec]
+	Container  containerTarget  `json:"container" yaml:"container"`   // the container of docker-exec
+	Compose    composeTarget    `json:"compose" yaml:"compose"`       // the service of compose-exec
+	Kubernetes kubernetesTarget `json:"kubernetes" yaml:"kubernetes"` // the pods of kubectl-exec
+
+	RequiresHealthy []string `json:"requires_healthy" yaml:"requires_healthy"` // the docker containers must be healthy, or running without health checks, like [db, api]
+	HealthyWait     duration `json:"healthy_wait" yaml:"healthy_wait"`         // delay the run until the containers are healthy up to the duration, skipped if 0 or still not healthy
+
+	HostNamespaces []string `json:"host_namespaces" yaml:"host_namespaces"` // [mount, pid, uts, net, ipc, cgroup] run in the namespaces of the host via --host-proc in docker mode, like [mount, pid]
+
+	PingURL   string `json:"ping_url" yaml:"ping_url"`     // the url of healthchecks.io or cronitor
+	PingStyle string `json:"ping_style" yaml:"ping_style"` // [healthchecks(default), cronitor]
+
+	Lock    string   `json:"lock" yaml:"lock"`         // [redis, etcd, consul, file] run on only one of the hosts with the same job, disabled if empty
+	LockKey string   `json:"lock_key" yaml:"lock_key"` // cron-cli:lock:<name> by default, or an absolute path for lock: file
+	LockTTL duration `json:"lock_ttl" yaml:"lock_ttl"` // the max time the lock is held, must be longer than the run, 10m by default
+
+	Before    string `json:"before" yaml:"before"`         // the command executed before the command, which is skipped if before fails
+	After     string `json:"after" yaml:"after"`           // the command executed after every run
+	OnSuccess string `json:"on_success" yaml:"on_success"` // the command executed after the command succeeded
+	OnFailure string `json:"on_failure" yaml:"on_failure"` // the command executed after the command failed
+
+	MailTo string `json:"mailto" yaml:"mailto"`   // send the output to the addresses, separated by commas
+	MailOn string `json:"mail_on" yaml:"mail_on"` // [output(default), failure, always]
+
+	Webhooks     []webhookConfig  `json:"webhooks" yaml:"webhooks"`           // notify the events of the job, besides the global webhooks
+	SlackChannel string           `json:"slack_channel" yaml:"slack_channel"` // override the channel of slack
+	Telegram     telegramSettings `json:"telegram" yaml:"telegram"`           // override the non-empty fields of the global telegram
+	Severity     string           `json:"severity" yaml:"severity"`           // [critical, error, warning, info], incidents of pagerduty/opsgenie are raised if set
+
+	NotifyOutputLines int    `json:"notify_output_lines" yaml:"notify_output_lines"` // the last N lines of output in notifications, 20 by default, negative to disable
+	MessageTemplate   string `json:"message_template" yaml:"message_template"`       // text/template of notification messages, like "{{.Job}} {{.Event}} on {{.Hostname}}"
+
+	AlertAfterFailures int      `json:"alert_after_failures" yaml:"alert_after_failures"` // alert only after N consecutive failures, 1 by default
+	AlertThrottle      duration `json:"alert_throttle" yaml:"alert_throttle"`             // at most one failure alert in the period, like 1h
+	AlertDedupe        bool     `json:"alert_dedupe" yaml:"alert_dedupe"`                 // drop the failure alert identical to the last one, until recovered
+
+	CircuitBreaker  int      `json:"circuit_breaker" yaml:"circuit_breaker"`   // disable the job for the cool-down after N consecutive failures, disabled if 0
+	CircuitCooldown duration `json:"circuit_cooldown" yaml:"circuit_cooldown"` // 10m by default, the next run after it closes the circuit if succeeded, or opens again
+
+	DailyBudget duration `json:"daily_budget" yaml:"daily_budget"` // skip the firings once the total runtime of the day exceeds it, alerted once a day, like 2h
+	Debounce    duration `json:"debounce" yaml:"debounce"`         // the triggers (via /jobs/run) within the quiet period collapse into one run after the last one, like 30s
+
+	StdoutLog string  `json:"stdout_log" yaml:"stdout_log"`
+	StderrLog string  `json:"stderr_log" yaml:"stderr_log"`
+	logger    *logger // opened lazily, see log()
+
+	id             cron.EntryID
+	task           *Task
+	configFile     string
+	definition     string // the config when loaded, the job is kept by Reload if unchanged
+	shellFile      string
+	savedShellFile string     // the shell file written, see saveShellFile
+	resourceMu     sync.Mutex // the logger and the shell file
+	history        *runHistory
+	state          *jobState
+	debouncer      debouncer
+	pending        int32 // 1 until the reload loading the job finished, the firings before are dropped
 }
 
-func (job *job) saveShellFile() {
-	if job.shellFile != "" {
-		path := job.shellFile
-		if job.task.InDocker() {
-			path = filepath.Join(job.task.rootPathInDocker, job.shellFile)
-		}
-		_ = os.WriteFile(path, []byte(job.Command), 0x644)
+// saveShellFile writes the command to the shell file on the first run, not for every job when loading
+func (job *job) saveShellFile() error {
+	job.resourceMu.Lock()
+	defer job.resourceMu.Unlock()
+	if job.shellFile == "" || job.savedShellFile == job.shellFile {
+		return nil
 	}
+
+	path := job.shellFile
+	if job.task.InDocker() {
+		path = filepath.Join(job.task.rootPathInDocker, job.shellFile)
+	}
+	if err := os.WriteFile(path, job.shellScript(job.Command), 0o600); err != nil {
+		return err
+	}
+	job.savedShellFile = job.shellFile
+	return nil
 }
 
 func (job *job) deleteShellFile() {
-	if job.shellFile != "" {
-		path := job.shellFile
+	job.removeShellFile(job.shellFile)
+}
+
+func (job *job) removeShellFile(shellFile string) {
+	if shellFile != "" {
+		path := shellFile
 		if job.task.InDocker() {
-			path = filepath.Join(job.task.rootPathInDocker, job.shellFile)
+			path = filepath.Join(job.task.rootPathInDocker, shellFile)
 		}
 
 		_ = os.Remove(path)
 	}
 }
 
+// command returns the actual command of the run, the script is the (expanded) command in the shell file
+func (job *job) command(script, shellFile string) ([]string, error) {
+	switch job.Type {
+	case "docker-exec":
+		return job.task.dockerExec([]string{job.Container.Name}, job.Container.User, job.Container.Env, script), nil
+	case "compose-exec":
+		containers, err := job.task.composeContainers(job.Compose)
+		if err != nil {
+			return nil, err
+		}
+		if !job.Compose.All {
+			containers = containers[:1]
+		}
+		return job.task.dockerExec(containers, job.Compose.User, nil, script), nil
+	case "kubectl-exec":
+		pods, err := job.Kubernetes.pods()
+		if err != nil {
+			return nil, err
+		}
+		if !job.Kubernetes.All {
+			pods = pods[:1]
+		}
+		return job.Kubernetes.exec(pods, script), nil
+	}
+	if job.ScriptStdin {
+		return append(job.shell(), job.shellArgs("-")...), nil
+	}
+	return append(job.shell(), job.shellArgs(shellFile)...), nil
+}
+
 func (job *job) Run() {
-	ctx := job.task.quitSignalCtx
+	// the previous job still runs until replaced
+	if atomic.LoadInt32(&job.pending) == 1 {
+		return
+	}
+	defer job.task.running.done(job.task.running.add(job.Name))
+
+	// killed by watchQuit after the stopping timeout
+	ctx, kill := context.WithCancel(context.Background())
+	defer kill()
 	// deadline if job.Timeout is valid.
 	if job.Timeout > 0 {
 		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(job.task.quitSignalCtx, time.Duration(job.Timeout)*time.Millisecond)
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(job.Timeout)*time.Millisecond)
 		defer cancel()
 	}
 
+	runID := newRunID()
+	log := job.log().with("run_id", runID)
+
+	if job.task.isPaused() {
+		job.skip(log, "paused", map[string]any{"run_id": runID})
+		return
+	}
+
+	if job.state.data().Disabled {
+		job.skip(log, "disabled", map[string]any{"run_id": runID})
+		return
+	}
+
+	if until := job.state.data().CircuitOpenUntil; time.Now().Before(until) {
+		job.skip(log, "circuit open", map[string]any{"run_id": runID, "until": until})
+		return
+	}
+
+	if used := job.state.budgetUsed(budgetDay(time.Now())); job.DailyBudget > 0 && used >= time.Duration(job.DailyBudget) {
+		job.skip(log, "daily budget exceeded", map[string]any{"run_id": runID, "used": used.String(), "daily_budget": job.DailyBudget.String()})
+		return
+	}
+
+	if err := job.waitHealthy(log); err != nil {
+		job.skip(log, "containers not healthy", map[string]any{"run_id": runID, "error": err.Error()})
+		return
+	}
+
+	release, locked, err := job.acquireLock()
+	if err != nil {
+		log.Error(err, "lock fail", "name", job.Name, "key", job.lockKey())
+		job.skip(log, "lock fail", map[string]any{"run_id": runID, "lock": job.Lock})
+		return
+	} else if !locked {
+		job.skip(log, "locked by others", map[string]any{"run_id": runID, "lock": job.Lock})
+		return
+	}
+	defer release(log)
+
+	if ok, last := job.state.claimRun(time.Now(), time.Duration(job.MinInterval)); !ok {
+		job.skip(log, "min interval", map[string]any{"run_id": runID, "last_run_at": last, "min_interval": job.MinInterval.String()})
+		return
+	}
+
+	if err := job.runHook(log, "before", job.Before, runRecord{RunID: runID}, nil); err != nil {
+		job.skip(log, "before hook failed", map[string]any{"run_id": runID})
+		return
+	}
+
+	job.task.events.publish(eventJobStarted, job.Name, map[string]any{"schedule": job.Schedule, "run_id": runID})
+
+	script, shellFile, err := job.expandCommand(runID)
+	if err != nil {
+		log.Error(err, "expand command fail", "name", job.Name)
+	} else if shellFile != job.shellFile {
+		defer job.removeShellFile(shellFile)
+	}
+
+	// resolved on every run, the containers may be recreated between firings
 	var actualCommand []string
-	if job.task.InDocker() {
-		actualCommand = []string{"nsenter", "-t", "1", "-m", "-u", "-n", "-i", "/usr/bin/sh", job.shellFile}
-	} else if runtime.GOOS == "windows" {
-		actualCommand = []string{"c:\\windows\\system32\\cmd.exe", job.shellFile}
-	} else {
-		actualCommand = []string{"/usr/bin/sh", job.shellFile}
+	if err == nil {
+		if actualCommand, err = job.command(script, shellFile); err != nil {
+			log.Error(err, "resolve command fail", "name", job.Name, "type", job.Type)
+		}
+	}
+	if err != nil {
+		output := getOutputBuffer()
+		defer output.release()
+		record := runRecord{RunID: runID, StartedAt: time.Now(), ExitCode: -1, Error: err.Error()}
+		job.reportError("error", err, "resolve command fail", map[string]any{"run_id": runID, "exit_code": record.ExitCode})
+		job.finish(log, record, output)
+		return
 	}
 
-	var truncatedCmd = truncateText(job.Command, 40)
-	job.logger.Info("executing", "schedule", job.Schedule, "command", strings.Join(actualCommand, " "), "id", job.id)
+	var truncatedCmd = log.command(job.Command)
+	log.Info("executing", "schedule", job.Schedule, "command", strings.Join(actualCommand, " "), "id", job.id)
 
 	cmd := exec.CommandContext(ctx, actualCommand[0], actualCommand[1:]...)
-	if !job.task.InDocker() {
+	setProcessGroup(cmd)
+	if !job.task.InDocker() && job.Type == "" {
 		cmd.Dir = job.WorkDirectory
 	}
-	cmd.Env = append(os.Environ(), job.Env...)
-	cmd.Stdout = job.logger.stdout("command", truncatedCmd, "id", job.id)
-	cmd.Stderr = job.logger.stderr("command", truncatedCmd, "id", job.id)
+	cmd.Env = append(append(os.Environ(), job.Env...), "CRON_RUN_ID="+runID)
+	if job.ScriptStdin && job.Type == "" {
+		cmd.Stdin = strings.NewReader(script)
+	}
+	output := getOutputBuffer()
+	defer output.release()
+	cmd.Stdout = io.MultiWriter(log.stdout("command", truncatedCmd, "id", job.id), output)
+	cmd.Stderr = io.MultiWriter(log.stderr("command", truncatedCmd, "id", job.id), output)
+
+	pingFinish := job.startPing(log)
+	record := runRecord{RunID: runID, StartedAt: time.Now()}
+	job.notify(log, notifyStart, record, nil)
+	err = job.execute(ctx, log, cmd, record, kill, shellFile)
+	record.Duration = time.Since(record.StartedAt)
+	if state := cmd.ProcessState; state != nil {
+		record.ExitCode = state.ExitCode()
+		record.UserTime = state.UserTime()
+		record.SystemTime = state.SystemTime()
+		record.MaxRSS = maxRSS(state)
+	} else {
+		record.ExitCode = -1
+	}
+	if err != nil {
+		record.Error = err.Error()
+		log.Error(err, "command execution fail", "schedule", job.Schedule, "command", truncatedCmd, "id", job.id)
+		job.reportError("error", err, "command execution fail", map[string]any{"run_id": runID, "exit_code": record.ExitCode})
+	}
+	pingFinish(record.success())
+
+	if record.success() {
+		_ = job.runHook(log, "on_success", job.OnSuccess, record, output)
+	} else {
+		_ = job.runHook(log, "on_failure", job.OnFailure, record, output)
+	}
+	_ = job.runHook(log, "after", job.After, record, output)
+
+	job.finish(log, record, output)
+}
 
-	if err := cmd.Run(); err != nil {
-		job.logger.Error(err, "command execution fail", "schedule", job.Schedule, "command", truncatedCmd, "id", job.id)
+// execute starts the command and waits for it
+func (job *job) execute(ctx context.Context, log *logger, cmd *exec.Cmd, record runRecord, kill context.CancelFunc, shellFile string) error {
+	if err := cmd.Start(); err != nil {
+		return err
 	}
+	// recorded for the next instance if this one crashed
+	job.state.addProcess(processRecord{PID: cmd.Process.Pid, RunID: record.RunID, ShellFile: shellFile, StartedAt: record.StartedAt})
+	job.task.saveStates()
 
+	done := job.watchQuit(log, cmd, kill)
+	go func() {
+		// the context kills the shell only, the children like pg_dump are killed with the process group
+		select {
+		case <-done:
+		case <-ctx.Done():
+			_ = killProcess(cmd.Process)
+		}
+	}()
+	err := cmd.Wait()
+	close(done)
+	job.state.removeProcess(cmd.Process.Pid)
+	return err
 }
 
-func (job *job) makeLogger(defaultLogger *logger) (err error) {
-	job.logger, err = newLogger(job.StdoutLog, job.StderrLog)
+// watchQuit terminates the command when quitting, and kills it after the stopping timeout.
+// Close the returned channel after the command exited.
+func (job *job) watchQuit(log *logger, cmd *exec.Cmd, kill context.CancelFunc) chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-done:
+			return
+		case <-job.task.quitSignalCtx.Done():
+		}
+
+		timeout := job.stoppingTimeout()
+		log.Info("terminating", "name", job.Name, "id", job.id, "stopping_timeout", timeout.String())
+		_ = terminate(cmd.Process)
 
-	if (job.StdoutLog == "" && job.StderrLog == "") || err != nil {
-		job.logger = defaultLogger
+		select {
+		case <-done:
+		case <-time.After(timeout):
+			log.Error(fmt.Errorf("still running after %s", timeout), "force killed", "name", job.Name, "id", job.id)
+			kill()
+		}
+	}()
+	return done
+}
+
+func (job *job) stoppingTimeout() time.Duration {
+	if job.StoppingTimeout > 0 {
+		return time.Duration(job.StoppingTimeout)
+	}
+	return job.task.stoppingTimeout()
+}
+
+// skip logs and publishes the skipped firing
+func (job *job) skip(log *logger, reason string, data map[string]any) {
+	if data == nil {
+		data = map[string]any{}
+	}
+	data["reason"] = reason
+
+	log.Info("skip", "name", job.Name, "id", job.id, "reason", reason)
+	job.task.events.publish(eventJobSkipped, job.Name, data)
+	job.task.statsd.count("skipped", 1, "job", job.Name)
+}
+
+// finish handles the result of a run: history, metrics, notifications and events
+func (job *job) finish(log *logger, record runRecord, output *outputBuffer) {
+	failures, alerted := job.state.update(record)
+	defer job.task.saveStates()
+	job.checkDuration(log, record)
+	job.history.add(record)
+
+	result := "success"
+	if !record.success() {
+		result = "failure"
+	}
+	job.task.metrics.set("cron_job_last_duration_seconds", record.Duration.Seconds(), "job", job.Name)
+	job.task.metrics.inc("cron_job_runs_total", "job", job.Name, "result", result)
+	job.task.statsd.count("runs", 1, "job", job.Name, "result", result)
+	job.task.statsd.timing("duration", record.Duration, "job", job.Name, "result", result)
+
+	job.mail(log, record, output)
+	if record.success() {
+		job.notify(log, notifySuccess, record, output)
+		if alerted {
+			log.Info("recovered", "name", job.Name, "consecutive_failures", failures)
+			job.task.events.publish(eventJobRecovered, job.Name, map[string]any{"run_id": record.RunID, "consecutive_failures": failures})
+			job.notify(log, notifyRecovery, record, output)
+		}
+	} else if failures < job.AlertAfterFailures {
+		log.Info("failure alert suppressed", "name", job.Name, "consecutive_failures", failures, "alert_after_failures", job.AlertAfterFailures)
+	} else if ok, reason := job.state.allowAlert(time.Now(), alertFingerprint(record, output), time.Duration(job.AlertThrottle), job.AlertDedupe); !ok {
+		log.Info("failure alert suppressed", "name", job.Name, "reason", reason)
+	} else {
+		job.notify(log, notifyFailure, record, output)
+	}
+	if !record.success() && job.CircuitBreaker > 0 && failures >= job.CircuitBreaker {
+		job.openCircuit(log, record, output, failures)
+	}
+	if used, exceeded := job.state.addRuntime(budgetDay(record.StartedAt), record.Duration, time.Duration(job.DailyBudget)); exceeded {
+		job.exceedBudget(log, record, output, used)
+	}
+
+	job.task.events.publish(eventJobFinished, job.Name, map[string]any{
+		"run_id":    record.RunID,
+		"exit_code": record.ExitCode,
+		"duration":  record.Duration.String(),
+		"error":     record.Error,
+	})
+	log.Info("executed", "name", job.Name, "id", job.id, "exit_code", record.ExitCode, "duration", record.Duration.String(),
+		"user_time", record.UserTime.String(), "system_time", record.SystemTime.String(), "max_rss", record.MaxRSS)
+}
+
+// openCircuit disables the job for the cool-down
+func (job *job) openCircuit(log *logger, record runRecord, output *outputBuffer, failures int) {
+	cooldown := time.Duration(job.CircuitCooldown)
+	if cooldown <= 0 {
+		cooldown = defaultCircuitCooldown
+	}
+	until := time.Now().Add(cooldown)
+	job.state.openCircuit(until)
+
+	log.Warn("circuit open", "name", job.Name, "consecutive_failures", failures, "until", until)
+	job.task.events.publish(eventCircuitOpen, job.Name, map[string]any{"run_id": record.RunID, "consecutive_failures": failures, "until": until})
+	job.task.statsd.count("circuit_open", 1, "job", job.Name)
+	job.notify(log, notifyCircuit, record, output)
+}
+
+// exceedBudget alerts the daily budget exceeded, the firings are skipped until tomorrow
+func (job *job) exceedBudget(log *logger, record runRecord, output *outputBuffer, used time.Duration) {
+	log.Warn("daily budget exceeded", "name", job.Name, "used", used.String(), "daily_budget", job.DailyBudget.String())
+	job.task.events.publish(eventBudget, job.Name, map[string]any{"run_id": record.RunID, "used": used.String(), "daily_budget": job.DailyBudget.String()})
+	job.task.statsd.count("budget_exceeded", 1, "job", job.Name)
+	job.notify(log, notifyBudget, record, output)
+}
+
+// makeLogger resets the logger of the job, the log files of the job are opened by log() on the first use
+func (job *job) makeLogger() {
+	job.resourceMu.Lock()
+	defer job.resourceMu.Unlock()
+	job.logger = nil
+}
+
+// log returns the logger of the job, the logger of the task if no log files or failed to open them
+func (job *job) log() *logger {
+	job.resourceMu.Lock()
+	defer job.resourceMu.Unlock()
+	if job.logger != nil {
+		return job.logger
+	}
+
+	job.logger = job.task.logger
+	if job.StdoutLog != "" || job.StderrLog != "" {
+		if l, err := newLogger(job.StdoutLog, job.StderrLog, job.task.logger.options); err != nil {
+			job.task.logger.Error(err, "open log files fail", "name", job.Name, "stdout_log", job.StdoutLog, "stderr_log", job.StderrLog)
+		} else {
+			job.logger = l
+		}
 	}
-	return
+	return job.logger
 }
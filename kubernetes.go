@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// kubernetesTarget is the pods matched by the label selector, resolved on every run.
+// kubectl uses the in-cluster credentials of the service account if kubeconfig is empty and $KUBECONFIG is not set.
+type kubernetesTarget struct {
+	Namespace  string `json:"namespace" yaml:"namespace"`   // default by default
+	Selector   string `json:"selector" yaml:"selector"`     // the label selector, like app=api,tier=web
+	Container  string `json:"container" yaml:"container"`   // the container of the pods, the default container by default
+	All        bool   `json:"all" yaml:"all"`               // run in all matched pods, the oldest one by default
+	Kubeconfig string `json:"kubeconfig" yaml:"kubeconfig"` // the path of kubeconfig
+	Context    string `json:"context" yaml:"context"`       // the context of kubeconfig
+}
+
+func (k kubernetesTarget) validate() error {
+	if k.Selector == "" {
+		return fmt.Errorf("kubernetes.selector required")
+	}
+	return nil
+}
+
+// kubectl returns the kubectl command with the credentials and the namespace
+func (k kubernetesTarget) kubectl(args ...string) []string {
+	command := []string{"kubectl"}
+	if k.Kubeconfig != "" {
+		command = append(command, "--kubeconfig", k.Kubeconfig)
+	}
+	if k.Context != "" {
+		command = append(command, "--context", k.Context)
+	}
+	if k.Namespace != "" {
+		command = append(command, "--namespace", k.Namespace)
+	}
+	return append(command, args...)
+}
+
+// pods returns the names of the running pods, the oldest first
+func (k kubernetesTarget) pods() ([]string, error) {
+	args := k.kubectl("get", "pods",
+		"--selector", k.Selector,
+		"--field-selector", "status.phase=Running",
+		"--sort-by", ".metadata.creationTimestamp",
+		"--output", "jsonpath={.items[*].metadata.name}",
+	)
+	out, err := runCLI(args[0], args[1:]...)
+	if err != nil {
+		return nil, err
+	}
+
+	names := strings.Fields(out)
+	if len(names) <= 0 {
+		return nil, fmt.Errorf("no running pods of selector %s", k.Selector)
+	}
+	return names, nil
+}
+
+// exec returns the command executing the shell command in the pods one by one
+func (k kubernetesTarget) exec(pods []string, command string) []string {
+	args := k.kubectl("exec")
+	if k.Container != "" {
+		args = append(args, "--container", k.Container)
+	}
+	return execEach(args, pods, []string{"--", "/bin/sh", "-c"}, command)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultLeaderKey = "cron-cli:leader"
+	defaultLeaderTTL = 15 * time.Second
+)
+
+var leaderBackends = []string{"redis", "etcd"}
+
+// leaderSettings elects one leader of the instances with the same key, only the leader schedules jobs.
+// The election starts with the cron, the changes of reloads are applied after restarted.
+type leaderSettings struct {
+	Lock string   `json:"lock" yaml:"lock"` // [redis, etcd] the backend of the election, disabled if empty
+	Key  string   `json:"key" yaml:"key"`   // cron-cli:leader by default
+	TTL  duration `json:"ttl" yaml:"ttl"`   // 15s by default, the leader renews every ttl/3, a standby takes over within ttl after the leader died
+}
+
+// renewer is a locker which extends the key held by the token
+type renewer interface {
+	// renew extends the key to ttl, returns false if the key is not held by the token anymore
+	renew(key, token string, ttl time.Duration) (bool, error)
+}
+
+// isLeader returns true if the election is disabled or this instance is the leader
+func (t *Task) isLeader() bool {
+	return !t.electing || atomic.LoadInt32(&t.leading) == 1
+}
+
+// startElection campaigns until ctx is done, the cron is started when elected and stopped when the leadership is lost.
+func (t *Task) startElection(ctx context.Context) error {
+	options := t.getSettings().LeaderElection
+	l, err := t.locker(options.Lock)
+	if err != nil {
+		return err
+	}
+	r, ok := l.(renewer)
+	if !ok {
+		return fmt.Errorf("the lock %s can not be used for the leader election", options.Lock)
+	}
+
+	key := options.Key
+	if key == "" {
+		key = defaultLeaderKey
+	}
+	ttl := time.Duration(options.TTL)
+	if ttl <= 0 {
+		ttl = defaultLeaderTTL
+	}
+	hostname, _ := os.Hostname()
+	token := fmt.Sprintf("%s/%d/%s", hostname, os.Getpid(), newRunID())
+	log := t.logger.with("lock", l.String(), "key", key)
+	t.electing = true
+
+	t.metrics.set("cron_leader", 0)
+	t.goBackground(func() {
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+
+		var renewed time.Time
+		for {
+			if atomic.LoadInt32(&t.leading) == 0 {
+				if ok, err := l.lock(key, token, ttl); err != nil {
+					log.Error(err, "campaign fail")
+				} else if ok {
+					renewed = time.Now()
+					t.setLeading(log, true)
+				}
+			} else if ok, err := r.renew(key, token, ttl); err != nil {
+				log.Error(err, "renew leadership fail")
+				// the key may be expired and taken by others, step down before that
+				if time.Since(renewed) > ttl*2/3 {
+					t.setLeading(log, false)
+				}
+			} else if !ok {
+				t.setLeading(log, false)
+			} else {
+				renewed = time.Now()
+			}
+
+			select {
+			case <-ctx.Done():
+				if atomic.LoadInt32(&t.leading) == 1 {
+					// release for a fast failover
+					if err := l.unlock(key, token, 0); err != nil {
+						log.Error(err, "resign leadership fail")
+					}
+					log.Info("resigned leadership")
+				}
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+	log.Info("cron standby, campaigning for leadership", "ttl", ttl.String())
+	return nil
+}
+
+func (t *Task) setLeading(log *logger, leading bool) {
+	t.reloadMu.Lock()
+	defer t.reloadMu.Unlock()
+
+	if leading {
+		atomic.StoreInt32(&t.leading, 1)
+		if !t.isDraining() && atomic.LoadInt32(&t.halted) == 0 {
+			t.Cron.Start()
+		}
+		t.metrics.set("cron_leader", 1)
+		log.Info("became leader, cron start")
+	} else {
+		atomic.StoreInt32(&t.leading, 0)
+		t.Cron.Stop()
+		t.metrics.set("cron_leader", 0)
+		log.Info("lost leadership, cron standby")
+	}
+	t.events.publish(eventLeader, "", map[string]any{"leader": leading})
+}
@@ -1,13 +1,16 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 )
 
 func (t *Task) LoadArguments(args []string) error {
+	t.arguments = args
 	jobs, err := t.parseArguments(args)
 	if err != nil {
 		return err
@@ -21,6 +24,7 @@ func (t *Task) LoadArguments(args []string) error {
 }
 
 func (t *Task) LoadConfigs(configs ...string) error {
+	t.configs = configs
 	if len(configs) <= 0 {
 		return nil
 	}
@@ -89,3 +93,130 @@ func findFiles(path string) ([]string, error) {
 
 	return files, err
 }
+
+// reloadState keeps the previous jobs of the arguments and configs while reloading, by configFile\x00name, guarded by reloadMu
+type reloadState struct {
+	previous map[string][]*job
+	jobs     []*job // the jobs of the arguments and configs reloaded, swapped in if the reload succeeds
+	added    []*job // the jobs scheduled by the reload, removed if it fails
+	replaced int
+}
+
+// fromConfigs returns true if the jobs of the config file are loaded from the arguments and configs,
+// not discovered from the docker containers nor the benchmark
+func fromConfigs(configFile string) bool {
+	return !strings.HasPrefix(configFile, discoveryConfigFile) && configFile != benchmarkConfigFile
+}
+
+// jobDefinition returns the config of the job before loaded, with the settings affecting the schedule
+func (t *Task) jobDefinition(j *job) (string, error) {
+	settings := t.loadingSettings()
+	data, err := json.Marshal(struct {
+		Job             *job
+		ScheduleAliases map[string]string
+		Workweek        string
+		Holidays        []string
+		Solar           solarSettings
+	}{j, settings.ScheduleAliases, settings.Workweek, settings.Holidays, settings.Solar})
+	if err != nil { // like anomaly_factor: .nan
+		return "", fmt.Errorf("invalid config: %w", err)
+	}
+	return string(data), nil
+}
+
+// reloadedJob returns the previous job of the same name in the config file while reloading,
+// unchanged is true if the definitions are the same, which is kept as is
+func (t *Task) reloadedJob(configFile, name, definition string) (previous *job, unchanged bool) {
+	if !fromConfigs(configFile) || t.reloading == nil {
+		return nil, false
+	}
+	key := configFile + "\x00" + name
+	jobs := t.reloading.previous[key]
+	for i, j := range jobs {
+		if j.definition == definition {
+			t.reloading.previous[key] = append(jobs[:i:i], jobs[i+1:]...)
+			return j, true
+		}
+	}
+	if len(jobs) > 0 {
+		t.reloading.replaced++
+		return jobs[0], false
+	}
+	return nil, false
+}
+
+// Reload loads the arguments and configs again, only the changed jobs are replaced.
+// The unchanged jobs keep their entries of cron, so the timing and the running runs are not interrupted.
+// The previous jobs are kept if loading fails.
+func (t *Task) Reload() error {
+	t.reloadMu.Lock()
+	defer t.reloadMu.Unlock()
+
+	t.logger.Info("reloading", "configs", t.configs)
+
+	// the previous jobs are kept in t.Jobs while loading, the discovery waits for reloadMu to change the other jobs
+	t.jobsMu.RLock()
+	state := &reloadState{previous: map[string][]*job{}}
+	for _, j := range t.Jobs {
+		if fromConfigs(j.configFile) {
+			key := j.configFile + "\x00" + j.Name
+			state.previous[key] = append(state.previous[key], j)
+		}
+	}
+	t.jobsMu.RUnlock()
+
+	t.reloading = state
+	defer func() { t.reloading = nil }()
+	// the running jobs keep the previous settings until all configs loaded
+	loading := &settings{}
+	t.settingsMu.Lock()
+	t.loading = loading
+	t.settingsMu.Unlock()
+	defer func() {
+		t.settingsMu.Lock()
+		t.loading = nil
+		t.settingsMu.Unlock()
+	}()
+
+	err := t.LoadArguments(t.arguments)
+	if err == nil {
+		err = t.LoadConfigs(t.configs...)
+	}
+
+	if err != nil {
+		t.removeJobs(state.added)
+		t.events.publish(eventReload, "", map[string]any{"error": err.Error()})
+		return err
+	}
+
+	t.settingsMu.Lock()
+	previousSettings := t.settings
+	t.settings, t.loading = *loading, nil
+	t.settingsMu.Unlock()
+	if loading.LeaderElection != previousSettings.LeaderElection {
+		t.logger.Warn("leader_election changed, applied after restarted")
+	}
+
+	// the previous jobs left are removed or replaced
+	var removed []*job
+	for _, jobs := range state.previous {
+		removed = append(removed, jobs...)
+	}
+	t.jobsMu.Lock()
+	var jobs []*job
+	for _, j := range t.Jobs {
+		if !fromConfigs(j.configFile) {
+			jobs = append(jobs, j)
+		}
+	}
+	t.Jobs = append(jobs, state.jobs...)
+	t.jobsMu.Unlock()
+	t.removeJobs(removed)
+	for _, j := range state.added {
+		atomic.StoreInt32(&j.pending, 0)
+	}
+
+	t.logger.Info("reloaded", "jobs", len(t.Jobs), "added", len(state.added)-state.replaced, "replaced", state.replaced, "removed", len(removed)-state.replaced)
+	t.events.publish(eventReload, "", map[string]any{"jobs": len(t.Jobs), "added": len(state.added) - state.replaced, "replaced": state.replaced, "removed": len(removed) - state.replaced})
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	defaultLockTTL = 10 * time.Minute
+	// the lock is kept at least lockMinHold after acquired, so the same firing of the
+	// other hosts with a slower clock can not acquire it after a quick run released
+	lockMinHold = 5 * time.Second
+)
+
+var lockBackends = []string{"redis", "etcd", "consul", "file"}
+
+// locker is a backend of the distributed locks
+type locker interface {
+	// lock acquires the key for ttl, returns false if it is held by others
+	lock(key, token string, ttl time.Duration) (bool, error)
+	// unlock releases the key if it is still held by the token, the key expires after keep if keep > 0
+	unlock(key, token string, keep time.Duration) error
+	String() string
+}
+
+func (t *Task) locker(backend string) (locker, error) {
+	settings := t.getSettings()
+	switch backend {
+	case "redis":
+		if settings.Redis.Addr == "" {
+			return nil, fmt.Errorf("redis.addr required for the lock of redis")
+		}
+		return newRedisLocker(settings.Redis), nil
+	case "etcd":
+		if len(settings.Etcd.Endpoints) <= 0 {
+			return nil, fmt.Errorf("etcd.endpoints required for the lock of etcd")
+		}
+		return newEtcdLocker(settings.Etcd), nil
+	case "consul":
+		if settings.Consul.Addr == "" {
+			return nil, fmt.Errorf("consul.addr required for the lock of consul")
+		}
+		return newConsulLocker(settings.Consul), nil
+	case "file":
+		if settings.LockDir == "" {
+			return nil, fmt.Errorf("lock_dir required for the lock of file")
+		}
+		return newFileLocker(settings.LockDir), nil
+	}
+	return nil, fmt.Errorf("invalid lock: %s, must be %v", backend, lockBackends)
+}
+
+func (job *job) lockKey() string {
+	if job.LockKey != "" {
+		return job.LockKey
+	}
+	return "cron-cli:lock:" + job.Name
+}
+
+// acquireLock acquires the distributed lock of the job, returns the function to release it.
+// ok is false if the lock is held by the others, or err if the backend fails.
+func (job *job) acquireLock() (release func(log *logger), ok bool, err error) {
+	if job.Lock == "" {
+		return func(*logger) {}, true, nil
+	}
+
+	l, err := job.task.locker(job.Lock)
+	if err != nil {
+		return nil, false, err
+	}
+
+	ttl := time.Duration(job.LockTTL)
+	if ttl <= 0 {
+		ttl = defaultLockTTL
+	}
+	key := job.lockKey()
+	hostname, _ := os.Hostname()
+	token := hostname + "/" + newRunID()
+
+	acquired := time.Now()
+	if ok, err = l.lock(key, token, ttl); err != nil {
+		return nil, false, fmt.Errorf("%s: %w", l.String(), err)
+	} else if !ok {
+		return nil, false, nil
+	}
+
+	// keep shorter than the half interval of firings, or the next firing of this host is skipped
+	hold := lockMinHold
+	if schedule := job.task.Cron.Entry(job.id).Schedule; schedule != nil {
+		if next := schedule.Next(acquired); !next.IsZero() {
+			if half := next.Sub(acquired) / 2; half < hold {
+				hold = half
+			}
+		}
+	}
+
+	return func(log *logger) {
+		keep := hold - time.Since(acquired)
+		if err := l.unlock(key, token, keep); err != nil {
+			log.Error(err, "unlock fail", "name", job.Name, "lock", l.String(), "key", key)
+		}
+	}, true, nil
+}
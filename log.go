@@ -1,20 +1,88 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"github.com/utahta/go-cronowriter"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zapio"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 type logger struct {
 	zapLogger *zap.Logger
+	options   logOptions
+	console   *consoleWriter // nil unless the logs are written to a terminal
 }
 
-func newLogger(stdoutPath, stderrPath string) (*logger, error) {
+type logOptions struct {
+	TimeFormat string         // [unix(default), unix-ms, unix-nano, rfc3339, rfc3339nano, iso8601] or a custom layout of golang, like "2006-01-02 15:04:05"
+	Location   *time.Location // nil means local
+
+	CommandLength int // the commands are truncated to the characters in logs, 0 means the full commands
+
+	Quiet   bool // only the errors are logged
+	NoColor bool // no colors of levels on the terminal, disabled if NO_COLOR is set too
+	Plain   bool // json logs on the terminal too, the same as the pipes
+}
+
+// enabled returns true if the level is logged
+func (o logOptions) enabled(level zapcore.Level) bool {
+	return !o.Quiet || level >= zap.ErrorLevel
+}
+
+func parseLogOptions(timeFormat, timezone string) (logOptions, error) {
+	options := logOptions{TimeFormat: timeFormat}
+	switch strings.ToLower(timezone) {
+	case "", "local":
+		options.Location = time.Local
+	case "utc":
+		options.Location = time.UTC
+	default:
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return options, fmt.Errorf("invalid timezone of log: %w", err)
+		}
+		options.Location = loc
+	}
+	return options, nil
+}
+
+func (o logOptions) timeEncoder() zapcore.TimeEncoder {
+	var layout string
+	switch strings.ToLower(o.TimeFormat) {
+	case "", "unix":
+		return zapcore.EpochTimeEncoder
+	case "unix-ms":
+		return zapcore.EpochMillisTimeEncoder
+	case "unix-nano":
+		return zapcore.EpochNanosTimeEncoder
+	case "rfc3339":
+		layout = time.RFC3339
+	case "rfc3339nano":
+		layout = time.RFC3339Nano
+	case "iso8601":
+		layout = "2006-01-02T15:04:05.000Z0700"
+	default:
+		layout = o.TimeFormat
+	}
+
+	loc := o.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	return func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		enc.AppendString(t.In(loc).Format(layout))
+	}
+}
+
+func newLogger(stdoutPath, stderrPath string, logOpts logOptions) (*logger, error) {
 	var l *zap.Logger
 	var err error
 
@@ -25,24 +93,45 @@ func newLogger(stdoutPath, stderrPath string) (*logger, error) {
 		})),
 	}
 
+	if stdoutPath == "" && stderrPath == "" && !logOpts.Plain && isTerminal(os.Stderr) { // human-readable logs with the running jobs
+		encoderConfig := zap.NewProductionEncoderConfig()
+		encoderConfig.EncodeTime = logOpts.timeEncoder()
+		if strings.ToLower(logOpts.TimeFormat) == "unix" || logOpts.TimeFormat == "" {
+			encoderConfig.EncodeTime = zapcore.TimeEncoderOfLayout("2006-01-02 15:04:05")
+		}
+		encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		if !logOpts.NoColor && colorEnabled() {
+			encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		}
+
+		console := newConsoleWriter(os.Stderr)
+		l = zap.New(zapcore.NewCore(zapcore.NewConsoleEncoder(encoderConfig), console, zap.LevelEnablerFunc(logOpts.enabled)), options...)
+		return &logger{zapLogger: l, options: logOpts, console: console}, nil
+	}
+
 	if stdoutPath == "" && stderrPath == "" { // output to console
-		if l, err = zap.NewProduction(options...); err != nil {
+		config := zap.NewProductionConfig()
+		config.EncoderConfig.EncodeTime = logOpts.timeEncoder()
+		if logOpts.Quiet {
+			config.Level = zap.NewAtomicLevelAt(zap.ErrorLevel)
+		}
+		if l, err = config.Build(options...); err != nil {
 			return nil, err
 		}
 		return &logger{
 			zapLogger: l,
+			options:   logOpts,
 		}, nil
 	}
 
 	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.EncodeTime = logOpts.timeEncoder()
 
 	if stderrPath == "" { // output all to a file
 		l = zap.New(zapcore.NewCore(
 			zapcore.NewJSONEncoder(encoderConfig),
 			zapcore.AddSync(cronowriter.MustNew(stdoutPath)),
-			zap.LevelEnablerFunc(func(level zapcore.Level) bool {
-				return true
-			})),
+			zap.LevelEnablerFunc(logOpts.enabled)),
 			options...,
 		)
 	} else { // output to 2 files
@@ -56,7 +145,7 @@ func newLogger(stdoutPath, stderrPath string) (*logger, error) {
 					zapcore.NewJSONEncoder(encoderConfig),
 					zapcore.AddSync(cronowriter.MustNew(stdoutPath)),
 					zap.LevelEnablerFunc(func(level zapcore.Level) bool {
-						return level < zap.ErrorLevel
+						return level < zap.ErrorLevel && logOpts.enabled(level)
 					}),
 				),
 				zapcore.NewCore(
@@ -75,7 +164,31 @@ func newLogger(stdoutPath, stderrPath string) (*logger, error) {
 		return nil, err
 	}
 
-	return &logger{zapLogger: l}, nil
+	return &logger{zapLogger: l, options: logOpts}, nil
+}
+
+// checkLogDir returns an error if the directory of the log files is not writable,
+// or can not be created under the nearest existing directory
+func checkLogDir(dir string) error {
+	for {
+		info, err := os.Stat(dir)
+		if err == nil && !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", dir)
+		} else if err == nil {
+			break
+		} else if parent := filepath.Dir(dir); !errors.Is(err, fs.ErrNotExist) || parent == dir {
+			return err
+		} else {
+			dir = parent
+		}
+	}
+
+	f, err := os.CreateTemp(dir, ".cron-*")
+	if err != nil {
+		return fmt.Errorf("the log directory %s is not writable: %w", dir, err)
+	}
+	_ = f.Close()
+	return os.Remove(f.Name())
 }
 
 func handleFields(args []any) []zap.Field {
@@ -96,10 +209,27 @@ func handleFields(args []any) []zap.Field {
 	return fields
 }
 
+// with returns a logger carrying the fields on every line
+// command truncates the command by the command length of the options
+func (l *logger) command(s string) string {
+	if l.options.CommandLength <= 0 {
+		return s
+	}
+	return truncateText(s, l.options.CommandLength)
+}
+
+func (l *logger) with(kv ...any) *logger {
+	return &logger{zapLogger: l.zapLogger.With(handleFields(kv)...), options: l.options, console: l.console}
+}
+
 func (l *logger) Info(msg string, args ...any) {
 	l.zapLogger.Info(msg, handleFields(args)...)
 }
 
+func (l *logger) Warn(msg string, args ...any) {
+	l.zapLogger.Warn(msg, handleFields(args)...)
+}
+
 func (l *logger) Error(err error, msg string, args ...any) {
 	l.zapLogger.Error(msg, handleFields(append(args, "error", err.Error()))...)
 }
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	mailOnOutput  = "output"
+	mailOnFailure = "failure"
+	mailOnAlways  = "always"
+)
+
+type smtpSettings struct {
+	Host     string `json:"host" yaml:"host"`
+	Port     int    `json:"port" yaml:"port"` // 25 by default
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+	From     string `json:"from" yaml:"from"` // cron@<hostname> by default
+}
+
+// recipients returns the addresses of mailto, separated by commas like MAILTO of crontab
+func (job *job) recipients() []string {
+	mailTo := job.MailTo
+	if mailTo == "" {
+		mailTo = job.task.getSettings().MailTo
+	}
+
+	var addresses []string
+	for _, address := range strings.Split(mailTo, ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
+
+// shouldMail decides by mail_on: [output(default), failure, always]
+//
+//	output: the run printed anything, or failed, the same as crontab
+//	failure: the run failed
+//	always: every run
+func (job *job) shouldMail(record runRecord, output *outputBuffer) bool {
+	mailOn := job.MailOn
+	if mailOn == "" {
+		mailOn = job.task.getSettings().MailOn
+	}
+
+	failed := !record.success()
+	switch mailOn {
+	case mailOnAlways:
+		return true
+	case mailOnFailure:
+		return failed
+	default:
+		return failed || output.Len() > 0
+	}
+}
+
+// mail sends the output of the run to the recipients in background
+func (job *job) mail(log *logger, record runRecord, output *outputBuffer) {
+	recipients := job.recipients()
+	if len(recipients) <= 0 || !job.shouldMail(record, output) {
+		return
+	}
+
+	config := job.task.getSettings().SMTP
+	if config.Host == "" {
+		log.Error(fmt.Errorf("smtp.host required"), "mail fail", "name", job.Name)
+		return
+	}
+
+	hostname, _ := os.Hostname()
+	from := config.From
+	if from == "" {
+		from = "cron@" + hostname
+	}
+	port := config.Port
+	if port <= 0 {
+		port = 25
+	}
+
+	var body strings.Builder
+	body.WriteString("From: " + from + "\r\n")
+	body.WriteString("To: " + strings.Join(recipients, ", ") + "\r\n")
+	body.WriteString(fmt.Sprintf("Subject: Cron <%s@%s> %s\r\n", job.Name, hostname, truncateText(firstLine(job.Command), 60)))
+	body.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	body.WriteString("X-Cron-Run-Id: " + record.RunID + "\r\n")
+	body.WriteString("\r\n")
+	body.WriteString(fmt.Sprintf("schedule: %s\r\nexit code: %d\r\nduration: %s\r\n", job.Schedule, record.ExitCode, record.Duration))
+	if record.Error != "" {
+		body.WriteString("error: " + record.Error + "\r\n")
+	}
+	body.WriteString("\r\n")
+	body.WriteString(strings.ReplaceAll(output.String(), "\n", "\r\n"))
+
+	job.task.goBackground(func() {
+		var auth smtp.Auth
+		if config.Username != "" {
+			auth = smtp.PlainAuth("", config.Username, config.Password, config.Host)
+		}
+		addr := net.JoinHostPort(config.Host, strconv.Itoa(port))
+		if err := smtp.SendMail(addr, auth, from, recipients, []byte(body.String())); err != nil {
+			log.Error(err, "mail fail", "name", job.Name, "to", recipients)
+		}
+	})
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
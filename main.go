@@ -1,15 +1,47 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/spf13/cobra"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
 )
 
 type cmdOptions struct {
 	rootPathInDocker string
+	hostProc         string
 	configs          []string
 	log              string
 	test             bool
+	testOptions      testOptions
+	benchmark        benchmarkOptions
+	http             httpOptions
+	auditLog         string
+	logTimeFormat    string
+	logTimezone      string
+	logCommandLength int
+	quiet            bool
+	noColor          bool
+	plain            bool
+	heartbeat        heartbeatOptions
+	sentryDSN        string
+	sentryEnv        string
+	statsd           statsdOptions
+	stateFile        string
+	stoppingTimeout  time.Duration
+	orphans          string
+	dockerDiscovery  bool
+	sidecar          string
+	tags             []string
 }
 
 func main() {
@@ -20,15 +52,43 @@ func main() {
 		Use:   "cron -- [schedule1] [command1] [args1...] -- [schedule2] [command2] [args2...]",
 		Short: "version: 1.1 \nexample: cron -- \"* * * * * *\" echo 'hello'",
 		PreRunE: func(cmd *cobra.Command, args []string) error {
-			if !cmd.PersistentFlags().Changed("config") && len(args) < 2 {
-				return fmt.Errorf("At least 2 arguments or set --config\n\n")
+			if !cmd.PersistentFlags().Changed("config") && len(args) < 2 && options.benchmark.Jobs <= 0 {
+				return fmt.Errorf("At least 2 arguments or set --config (or --benchmark-jobs)\n\n")
 			}
 			return nil
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 
-			task := buildTask(options.log, options.test)
+			logOpts, err := parseLogOptions(options.logTimeFormat, options.logTimezone)
+			if err != nil {
+				panic(err.Error())
+			}
+			logOpts.CommandLength = options.logCommandLength
+			logOpts.Quiet, logOpts.NoColor, logOpts.Plain = options.quiet, options.noColor, options.plain
+			task := buildTask(options.log, logOpts, options.test)
 			task.rootPathInDocker = options.rootPathInDocker
+			task.hostProcPath = options.hostProc
+			task.discovery = options.dockerDiscovery
+			task.sidecar = options.sidecar
+			task.test = options.testOptions
+			task.tags = options.tags
+			task.heartbeat = options.heartbeat
+			task.stopTimeout = options.stoppingTimeout
+			if err := task.SetStateFile(options.stateFile); err != nil {
+				panic(err.Error())
+			}
+			if err := task.SetOrphanPolicy(options.orphans); err != nil {
+				panic(err.Error())
+			}
+			if err := task.SetStatsd(options.statsd); err != nil {
+				panic(err.Error())
+			}
+			if err := task.SetSentry(options.sentryDSN, options.sentryEnv); err != nil {
+				panic(err.Error())
+			}
+			if err := task.SetAuditLog(options.auditLog); err != nil {
+				panic(err.Error())
+			}
 
 			if err := task.LoadArguments(args); err != nil {
 				panic(err.Error())
@@ -38,19 +98,164 @@ func main() {
 				panic(err.Error())
 			}
 
+			if err := task.LoadBenchmark(options.benchmark); err != nil {
+				panic(err.Error())
+			}
+
+			if err := task.StartServer(options.http); err != nil {
+				panic(err.Error())
+			}
 			task.Start()
+			task.ListenReloadSignal()
+			task.ListenDrainSignal()
 			task.ListenStopSignal(func() {
 				task.Stop()
 			})
 
 			task.Wait()
+			task.ReportBenchmark()
+			if task.TestFailed() {
+				os.Exit(1)
+			}
 		},
 	}
 
 	rootCmd.PersistentFlags().StringVar(&options.rootPathInDocker, "root-path-in-docker", "/", "What the mounted path of / of host os. Implied meaning: run this application in docker container")
+	rootCmd.PersistentFlags().StringVar(&options.hostProc, "host-proc", "", "the mounted path of /proc of host os for host_namespaces of jobs, default is <root-path-in-docker>/proc")
+	rootCmd.PersistentFlags().BoolVar(&options.dockerDiscovery, "docker-discovery", false, "add the jobs of the docker containers labeled cron.enabled=true when they start, like cron.job.<name>.schedule and cron.job.<name>.command or the yaml file of cron.config in them, removed when they die")
+	rootCmd.PersistentFlags().StringVar(&options.sidecar, "sidecar", "", "the name of a docker container, schedule its jobs of the cron.job.* labels and the yaml file of the cron.config label in it, like cron.config=/etc/cron.yaml, reloaded when it is redeployed")
 	rootCmd.PersistentFlags().StringSliceVarP(&options.configs, "config", "c", []string{}, "the path of config files or directories")
+	rootCmd.PersistentFlags().StringSliceVar(&options.tags, "tags", []string{}, "only the jobs with any of the tags, when starting (and --test), and for trigger, list and export, like db,nightly")
 	rootCmd.PersistentFlags().StringVarP(&options.log, "log", "l", "", "the path of log file")
-	rootCmd.PersistentFlags().BoolVar(&options.test, "test", false, "execute all commands immediately and quit")
+	rootCmd.PersistentFlags().BoolVar(&options.test, "test", false, "execute all commands immediately and quit, exit with 1 if any failed")
+	rootCmd.PersistentFlags().StringSliceVar(&options.testOptions.Jobs, "test-jobs", []string{}, "the names of jobs executed by --test, all by default, like backup,cleanup")
+	rootCmd.PersistentFlags().BoolVar(&options.testOptions.Parallel, "test-parallel", false, "execute the jobs of --test in parallel instead of one by one")
+	rootCmd.PersistentFlags().DurationVar(&options.testOptions.Timeout, "test-timeout", 0, "kill the jobs of --test running longer than it, the timeout of jobs if shorter, like 5m, disabled if 0")
+	rootCmd.PersistentFlags().IntVar(&options.benchmark.Jobs, "benchmark-jobs", 0, "add the synthetic jobs for a benchmark, then report the scheduling latency, the concurrent runs and the memory after --benchmark-duration, disabled if 0")
+	rootCmd.PersistentFlags().DurationVar(&options.benchmark.Duration, "benchmark-duration", time.Minute, "how long the benchmark runs before quitting")
+	rootCmd.PersistentFlags().StringVar(&options.benchmark.Schedule, "benchmark-schedule", "* * * * * *", "the schedule of the synthetic jobs")
+	rootCmd.PersistentFlags().StringVar(&options.benchmark.Command, "benchmark-command", "true", "the command of the synthetic jobs")
+	rootCmd.PersistentFlags().StringVar(&options.http.Addr, "http", "", "the listen address of http server (/metrics, /events), like :9100 (on 127.0.0.1), 0.0.0.0:9100 (requires --http-token) or unix:/run/cron.sock, disabled if empty")
+	rootCmd.PersistentFlags().StringVar(&options.http.Token, "http-token", os.Getenv("CRON_HTTP_TOKEN"), "the bearer token of the http server except /metrics, sent by the commands like trigger, default is $CRON_HTTP_TOKEN")
+	rootCmd.PersistentFlags().StringVar(&options.logTimeFormat, "log-time-format", "unix", "the timestamp format of logs: [unix, unix-ms, unix-nano, rfc3339, rfc3339nano, iso8601] or a custom layout of golang, like \"2006-01-02 15:04:05\"")
+	rootCmd.PersistentFlags().StringVar(&options.logTimezone, "log-timezone", "local", "the timezone of log timestamps: [local, utc] or a name of IANA, like Asia/Shanghai")
+	rootCmd.PersistentFlags().IntVar(&options.logCommandLength, "log-command-length", 40, "the commands are truncated to the characters in logs, 0 to log the full commands")
+	rootCmd.PersistentFlags().BoolVarP(&options.quiet, "quiet", "q", false, "log the errors only, including the stderr of commands")
+	rootCmd.PersistentFlags().BoolVar(&options.noColor, "no-color", false, "no colors on the terminal, also disabled by $NO_COLOR or TERM=dumb")
+	rootCmd.PersistentFlags().BoolVar(&options.plain, "plain", false, "json logs on the terminal like the pipes, instead of the human-readable logs with a table of the running jobs")
+	rootCmd.PersistentFlags().DurationVar(&options.stoppingTimeout, "stopping-timeout", defaultStoppingTimeout, "how long the running jobs are waited for after SIGTERM when quitting, then killed, overridden by stopping_timeout of configs")
+	rootCmd.PersistentFlags().StringVar(&options.stateFile, "state-file", "", "the path of state file, which keeps the run state of jobs across restarts")
+	rootCmd.PersistentFlags().StringVar(&options.orphans, "orphans", "warn", "the processes left behind by a crashed instance, recorded in --state-file: [warn, kill, adopt]")
+	rootCmd.PersistentFlags().StringVar(&options.auditLog, "audit-log", "", "the path of audit log file, which records reloads and manual triggers")
+	rootCmd.PersistentFlags().DurationVar(&options.heartbeat.Interval, "heartbeat-interval", 0, "the interval of scheduler heartbeats, like 30s, disabled if 0")
+	rootCmd.PersistentFlags().StringVar(&options.heartbeat.URL, "heartbeat-url", "", "the url requested on every heartbeat")
+	rootCmd.PersistentFlags().StringVar(&options.heartbeat.File, "heartbeat-file", "", "the file written with the unix timestamp on every heartbeat")
+	rootCmd.PersistentFlags().StringVar(&options.sentryDSN, "sentry-dsn", os.Getenv("SENTRY_DSN"), "the dsn of sentry to report job failures and panics, default is $SENTRY_DSN")
+	rootCmd.PersistentFlags().StringVar(&options.sentryEnv, "sentry-environment", os.Getenv("SENTRY_ENVIRONMENT"), "the environment of sentry events, default is $SENTRY_ENVIRONMENT")
+	rootCmd.PersistentFlags().StringVar(&options.statsd.Addr, "statsd", "", "the udp address of StatsD/DogStatsD, like 127.0.0.1:8125, disabled if empty")
+	rootCmd.PersistentFlags().StringVar(&options.statsd.Prefix, "statsd-prefix", "cron.", "the prefix of StatsD metric names")
+	rootCmd.PersistentFlags().StringSliceVar(&options.statsd.Tags, "statsd-tags", []string{}, "the global tags of DogStatsD, like env:prod,team:ops")
+	rootCmd.PersistentFlags().BoolVar(&options.statsd.DogStatsD, "dogstatsd", false, "emit the tags in the DogStatsD format, otherwise the job and result are in the metric names, like cron.<job>.<result>.runs")
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:          "trigger [name]",
+		Short:        "run a job (or the jobs of --tags) immediately via the http server of a running cron, requires --http",
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return callServer(options.http, "/jobs/run?name="+url.QueryEscape(args[0]))
+			} else if len(options.tags) > 0 {
+				return callServer(options.http, "/jobs/run?tags="+url.QueryEscape(strings.Join(options.tags, ",")))
+			}
+			return fmt.Errorf("the name of job or --tags required")
+		},
+	})
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:          "list",
+		Short:        "list the jobs (with --tags) of a running cron and their next firings, requires --http",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listJobs(options.http, options.tags)
+		},
+	})
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:          "history [name]",
+		Short:        "print the latest runs of a job of a running cron, requires --http",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listHistory(options.http, args[0])
+		},
+	})
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:          "export",
+		Short:        "print the yaml config of the jobs (with --tags) of a running cron, without the settings and the secrets, requires --http",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			body, err := requestServer(http.MethodGet, options.http, "/jobs/export?tags="+url.QueryEscape(strings.Join(options.tags, ",")))
+			if err != nil {
+				return err
+			}
+			_, err = os.Stdout.Write(body)
+			return err
+		},
+	})
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:          "enable [name]",
+		Short:        "enable a disabled job of a running cron, requires --http",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return callServer(options.http, "/jobs/enable?name="+url.QueryEscape(args[0]))
+		},
+	})
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:          "disable [name]",
+		Short:        "skip the firings of a job until enabled, kept across reloads and restarts (with --state-file), requires --http",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return callServer(options.http, "/jobs/disable?name="+url.QueryEscape(args[0]))
+		},
+	})
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:          "drain",
+		Short:        "stop scheduling, wait for the running jobs without deadline, then quit, requires --http (or send SIGUSR1)",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return callServer(options.http, "/drain")
+		},
+	})
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:          "pause",
+		Short:        "skip all firings of a running cron until resumed, requires --http",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return callServer(options.http, "/pause")
+		},
+	})
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:          "resume",
+		Short:        "resume the firings of a paused cron, requires --http",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return callServer(options.http, "/resume")
+		},
+	})
 
 	err := rootCmd.Execute()
 	if err != nil {
@@ -58,8 +263,8 @@ func main() {
 	}
 }
 
-func buildTask(logPath string, test bool) *Task {
-	log, err := newLogger(logPath, "")
+func buildTask(logPath string, logOpts logOptions, test bool) *Task {
+	log, err := newLogger(logPath, "", logOpts)
 	if err != nil {
 		panic("create logger error: " + err.Error())
 	}
@@ -68,3 +273,113 @@ func buildTask(logPath string, test bool) *Task {
 
 	return task
 }
+
+// callServer posts to the http server of a running cron
+func callServer(server httpOptions, path string) error {
+	body, err := requestServer(http.MethodPost, server, path)
+	if err != nil {
+		return err
+	}
+
+	var result map[string]string
+	if err = json.Unmarshal(body, &result); err != nil {
+		return err
+	}
+	fmt.Println(result["status"])
+	return nil
+}
+
+// requestServer sends the request to the http server of a running cron, returns the body of response
+func requestServer(method string, server httpOptions, path string) ([]byte, error) {
+	if server.Addr == "" {
+		return nil, fmt.Errorf("--http required")
+	}
+
+	client := http.DefaultClient
+	network, address := server.listenAddr()
+	host := address
+	if network == "unix" {
+		client = &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, network, address)
+			},
+		}}
+		host = "unix"
+	}
+
+	req, err := http.NewRequest(method, "http://"+host+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Cron-Actor", currentUser())
+	if server.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+server.Token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		var result map[string]string
+		if err = json.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("%s: %s", resp.Status, body)
+		}
+		return nil, errors.New(result["error"])
+	}
+	return body, nil
+}
+
+// listJobs prints the jobs of a running cron
+func listJobs(server httpOptions, tags []string) error {
+	body, err := requestServer(http.MethodGet, server, "/jobs?tags="+url.QueryEscape(strings.Join(tags, ",")))
+	if err != nil {
+		return err
+	}
+	var jobs []jobInfo
+	if err = json.Unmarshal(body, &jobs); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSCHEDULE\tTAGS\tNEXT\tLAST")
+	for _, j := range jobs {
+		next := j.Next.Format("2006-01-02 15:04:05")
+		if j.Disabled {
+			next = "disabled"
+		} else if j.Next.IsZero() {
+			next = "-"
+		}
+		last := "-"
+		if j.Last != nil {
+			last = fmt.Sprintf("%s (exit %d)", j.Last.StartedAt.Format("2006-01-02 15:04:05"), j.Last.ExitCode)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", j.Name, j.Schedule, strings.Join(j.Tags, ","), next, last)
+	}
+	return w.Flush()
+}
+
+func listHistory(server httpOptions, name string) error {
+	body, err := requestServer(http.MethodGet, server, "/jobs/history?name="+url.QueryEscape(name))
+	if err != nil {
+		return err
+	}
+	var records []runRecord
+	if err = json.Unmarshal(body, &records); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "RUN ID\tSTARTED\tDURATION\tEXIT CODE\tUSER TIME\tSYSTEM TIME\tMAX RSS\tERROR")
+	for _, r := range records {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%d\t%s\n", r.RunID, r.StartedAt.Format("2006-01-02 15:04:05"), r.Duration, r.ExitCode, r.UserTime, r.SystemTime, r.MaxRSS, r.Error)
+	}
+	return w.Flush()
+}
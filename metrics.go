@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	metricCounter = "counter"
+	metricGauge   = "gauge"
+)
+
+type metricFamily struct {
+	name   string
+	help   string
+	kind   string
+	values map[string]float64 // rendered labels => value
+}
+
+// metrics is a tiny registry rendered in the prometheus text format.
+type metrics struct {
+	mu       sync.Mutex
+	families map[string]*metricFamily
+}
+
+func newMetrics() *metrics {
+	return &metrics{families: map[string]*metricFamily{}}
+}
+
+func (m *metrics) register(kind, name, help string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.families[name]; !ok {
+		m.families[name] = &metricFamily{name: name, help: help, kind: kind, values: map[string]float64{}}
+	}
+}
+
+// renderLabels converts [k1, v1, k2, v2...] to k1="v1",k2="v2"
+func renderLabels(labels []string) string {
+	var pairs []string
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, labels[i]+"="+strconv.Quote(labels[i+1]))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (m *metrics) update(name string, fn func(float64) float64, labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	family, ok := m.families[name]
+	if !ok {
+		return
+	}
+	key := renderLabels(labels)
+	family.values[key] = fn(family.values[key])
+}
+
+func (m *metrics) inc(name string, labels ...string) {
+	m.add(name, 1, labels...)
+}
+
+func (m *metrics) add(name string, delta float64, labels ...string) {
+	m.update(name, func(v float64) float64 { return v + delta }, labels...)
+}
+
+func (m *metrics) set(name string, value float64, labels ...string) {
+	m.update(name, func(float64) float64 { return value }, labels...)
+}
+
+func (m *metrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.families))
+	for name := range m.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		family := m.families[name]
+		_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, family.help, name, family.kind)
+
+		keys := make([]string, 0, len(family.values))
+		for key := range family.values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if key == "" {
+				_, _ = fmt.Fprintf(w, "%s %v\n", name, family.values[key])
+			} else {
+				_, _ = fmt.Fprintf(w, "%s{%s} %v\n", name, key, family.values[key])
+			}
+		}
+	}
+}
+
+func (t *Task) registerMetrics() {
+	t.metrics.register(metricCounter, "cron_job_runs_total", "Total runs of the job, by result.")
+	t.metrics.register(metricGauge, "cron_job_last_duration_seconds", "Wall-clock duration of the last run.")
+	t.metrics.register(metricGauge, "cron_job_median_duration_seconds", "Median duration of the recent runs.")
+	t.metrics.register(metricCounter, "cron_job_slow_runs_total", "Runs taking longer than anomaly_factor times the median duration.")
+	t.metrics.register(metricGauge, "cron_heartbeat_timestamp_seconds", "Unix timestamp of the last heartbeat of the scheduler.")
+	t.metrics.register(metricGauge, "cron_leader", "1 if this instance is the elected leader, 0 if standby.")
+	t.metrics.register(metricGauge, "cron_paused", "1 if the firings are paused.")
+}
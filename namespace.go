@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// the namespaces of the host joined by nsenter, by the files under /proc/1/ns of the host
+var hostNamespaces = map[string]string{
+	"mount":  "mnt",
+	"pid":    "pid",
+	"uts":    "uts",
+	"net":    "net",
+	"ipc":    "ipc",
+	"cgroup": "cgroup",
+}
+
+// hostProc is the /proc of the host mounted in the container, <root-path-in-docker>/proc by default
+func (t *Task) hostProc() string {
+	if t.hostProcPath != "" {
+		return t.hostProcPath
+	}
+	return filepath.Join(t.rootPathInDocker, "proc")
+}
+
+// checkHostNamespaces validates the namespaces, and the privileges to join them
+func (job *job) checkHostNamespaces() error {
+	if len(job.HostNamespaces) <= 0 {
+		return nil
+	}
+	if !job.task.InDocker() {
+		return fmt.Errorf("host_namespaces of schedule: \"%s\" requires --root-path-in-docker", job.Schedule)
+	}
+
+	for _, ns := range job.HostNamespaces {
+		file, ok := hostNamespaces[ns]
+		if !ok {
+			return fmt.Errorf("host_namespaces of schedule: \"%s\" must be in [mount, pid, uts, net, ipc, cgroup], yours: %s", job.Schedule, ns)
+		}
+		path := filepath.Join(job.task.hostProc(), "1", "ns", file)
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("host_namespaces of schedule: \"%s\" requires the /proc of host mounted at %s (--host-proc) and a privileged container: %w", job.Schedule, job.task.hostProc(), err)
+		}
+	}
+	return nil
+}
+
+// nsenter returns the nsenter command joining the namespaces of the host, the working directory is in the host
+func (job *job) nsenter() []string {
+	args := []string{"nsenter"}
+	for _, ns := range job.HostNamespaces {
+		args = append(args, fmt.Sprintf("--%s=%s", ns, filepath.Join(job.task.hostProc(), "1", "ns", hostNamespaces[ns])))
+	}
+	if job.WorkDirectory != "" {
+		args = append(args, "--wd="+job.WorkDirectory)
+	}
+	return args
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	notifyStart    = "start"
+	notifySuccess  = "success"
+	notifyFailure  = "failure"
+	notifyRecovery = "recovery"        // the first success after failures
+	notifyCircuit  = "circuit_open"    // the job is disabled for the cool-down by the circuit breaker
+	notifyBudget   = "budget_exceeded" // the runtime of the day exceeds daily_budget, the firings are skipped until tomorrow
+
+	// the output excerpt of notifications
+	defaultOutputLines = 20
+	outputSnippetSize  = 3000
+)
+
+// notification is the data of a job event sent to the notifiers
+type notification struct {
+	Event    string    `json:"event"` // [start, success, failure, recovery, circuit_open, budget_exceeded]
+	Job      string    `json:"job"`
+	Schedule string    `json:"schedule"`
+	Command  string    `json:"command"`
+	RunID    string    `json:"run_id"`
+	Hostname string    `json:"hostname"`
+	ExitCode int       `json:"exit_code"`
+	Duration string    `json:"duration"`
+	Error    string    `json:"error,omitempty"`
+	Output   string    `json:"output,omitempty"` // the last lines of stdout and stderr
+	Time     time.Time `json:"time"`
+
+	Message string `json:"message,omitempty"` // rendered by message_template, the text of slack, telegram and incidents
+}
+
+// notifier sends the notifications to somewhere
+type notifier interface {
+	// accepts returns whether the notifier is interested in the event
+	accepts(event string) bool
+	send(n *notification) error
+	retries() int
+	String() string
+}
+
+func (job *job) notifiers() []notifier {
+	settings := job.task.getSettings()
+	var notifiers []notifier
+	for _, w := range append(append([]webhookConfig{}, settings.Webhooks...), job.Webhooks...) {
+		notifiers = append(notifiers, w)
+	}
+
+	if slack := settings.Slack; slack.enabled() {
+		channel := job.SlackChannel
+		if channel == "" {
+			channel = slack.Channel
+		}
+		notifiers = append(notifiers, slackNotifier{settings: slack, channel: channel})
+	}
+
+	if telegram := settings.Telegram.override(job.Telegram); telegram.enabled() {
+		notifiers = append(notifiers, telegramNotifier{settings: telegram})
+	}
+
+	// only the jobs with severity raise incidents
+	if job.Severity != "" {
+		if pagerduty := settings.PagerDuty; pagerduty.RoutingKey != "" {
+			notifiers = append(notifiers, pagerdutyNotifier{settings: pagerduty, severity: job.Severity})
+		}
+		if opsgenie := settings.Opsgenie; opsgenie.APIKey != "" {
+			notifiers = append(notifiers, opsgenieNotifier{settings: opsgenie, severity: job.Severity})
+		}
+	}
+	return notifiers
+}
+
+// notify sends the event of the run to all notifiers in background
+func (job *job) notify(log *logger, event string, record runRecord, output *outputBuffer) {
+	notifiers := job.notifiers()
+	if len(notifiers) <= 0 {
+		return
+	}
+
+	hostname, _ := os.Hostname()
+	n := &notification{
+		Event:    event,
+		Job:      job.Name,
+		Schedule: job.Schedule,
+		Command:  job.Command,
+		RunID:    record.RunID,
+		Hostname: hostname,
+		ExitCode: record.ExitCode,
+		Duration: record.Duration.String(),
+		Error:    record.Error,
+		Time:     time.Now(),
+	}
+	if output != nil {
+		n.Output = output.tailLines(job.outputLines(), outputSnippetSize)
+	}
+	if tpl := job.messageTemplate(); tpl != "" {
+		message, err := renderTemplate(tpl, n)
+		if err != nil {
+			log.Error(err, "render message template fail", "name", job.Name)
+		}
+		n.Message = message
+	}
+
+	for _, nt := range notifiers {
+		if !nt.accepts(event) {
+			continue
+		}
+		nt := nt
+		job.task.goBackground(func() {
+			sendWithRetries(log, nt, n)
+		})
+	}
+}
+
+// outputLines is the lines of output attached to the notifications, none if negative
+func (job *job) outputLines() int {
+	lines := job.NotifyOutputLines
+	if lines == 0 {
+		lines = job.task.getSettings().NotifyOutputLines
+	}
+	if lines == 0 {
+		lines = defaultOutputLines
+	}
+	return lines
+}
+
+func (job *job) messageTemplate() string {
+	if job.MessageTemplate != "" {
+		return job.MessageTemplate
+	}
+	return job.task.getSettings().MessageTemplate
+}
+
+func renderTemplate(text string, data any) (string, error) {
+	tpl, err := template.New("message").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template: %w", err)
+	}
+	var buf strings.Builder
+	if err = tpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// alertFingerprint identifies the identical alerts of a job
+func alertFingerprint(record runRecord, output *outputBuffer) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%d\n%s\n%s", record.ExitCode, record.Error, output.tail(outputSnippetSize))))
+	return hex.EncodeToString(sum[:])
+}
+
+func sendWithRetries(log *logger, nt notifier, n *notification) {
+	backoff := time.Second
+	for i := 0; ; i++ {
+		err := nt.send(n)
+		if err == nil {
+			return
+		}
+		if i >= nt.retries() {
+			log.Error(redactError(err), "notify fail", "notifier", nt.String(), "event", n.Event, "job", n.Job)
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// redactURL keeps the scheme and the host of the url, the userinfo, paths and queries may have the credentials,
+// like the bot token of telegram and the incoming webhook of slack
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "[redacted]"
+	}
+	redacted := u.Scheme + "://" + u.Host
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		redacted += "/[redacted]"
+	}
+	return redacted
+}
+
+// redactError redacts the url of the *url.Error returned by the http client, which is logged
+func redactError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s %s: %w", urlErr.Op, redactURL(urlErr.URL), urlErr.Err)
+	}
+	return err
+}
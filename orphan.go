@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// the policies of the processes left behind by a crashed instance
+var orphanPolicies = []string{
+	"warn",  // log only
+	"kill",  // terminate, then kill after the stopping timeout
+	"adopt", // watch until exited, and keep their shell files
+}
+
+func (t *Task) SetOrphanPolicy(policy string) error {
+	if !containsString(orphanPolicies, policy) {
+		return fmt.Errorf("--orphans must be %v, yours: %s", orphanPolicies, policy)
+	}
+	t.orphans = policy
+	return nil
+}
+
+// handleOrphans handles the processes recorded in the state file by the previous instance, then cleans up the stale shell files
+func (t *Task) handleOrphans() {
+	keep := map[string]bool{}
+	for name, state := range t.states.all() {
+		for _, p := range state.takeProcesses() {
+			if !processRunning(p.PID, p.ShellFile) {
+				continue
+			}
+
+			t.logger.Warn("orphan process found", "name", name, "pid", p.PID, "run_id", p.RunID, "started_at", p.StartedAt, "policy", t.orphans)
+			switch t.orphans {
+			case "kill":
+				t.killOrphan(name, p)
+			case "adopt":
+				keep[p.ShellFile] = true
+				state.addProcess(p)
+				go t.adoptOrphan(name, state, p)
+			default:
+				keep[p.ShellFile] = true
+			}
+		}
+	}
+	t.saveStates()
+	t.cleanShellFiles(keep)
+}
+
+func (t *Task) killOrphan(name string, p processRecord) {
+	process, err := os.FindProcess(p.PID)
+	if err != nil {
+		return
+	}
+	_ = terminate(process)
+
+	go func() {
+		defer process.Release()
+		timeout := t.stoppingTimeout()
+		for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+			if !processRunning(p.PID, p.ShellFile) {
+				t.logger.Info("orphan process terminated", "name", name, "pid", p.PID)
+				return
+			}
+		}
+		if err := killProcess(process); err != nil {
+			t.logger.Error(err, "kill orphan process fail", "name", name, "pid", p.PID)
+			return
+		}
+		t.logger.Warn("orphan process killed", "name", name, "pid", p.PID, "stopping_timeout", timeout.String())
+	}()
+}
+
+// adoptOrphan watches the process until it exited or quitting
+func (t *Task) adoptOrphan(name string, state *jobState, p processRecord) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.quitSignalCtx.Done():
+			return
+		case <-ticker.C:
+			if !processRunning(p.PID, p.ShellFile) {
+				state.removeProcess(p.PID)
+				t.saveStates()
+				t.logger.Info("adopted orphan process exited", "name", name, "pid", p.PID, "run_id", p.RunID)
+				return
+			}
+		}
+	}
+}
+
+// cleanShellFiles removes the shell files of the configs which are not used by the jobs, like .config.yaml-3.sh left by a crash
+func (t *Task) cleanShellFiles(keep map[string]bool) {
+	if t.testMode {
+		return
+	}
+
+	t.jobsMu.RLock()
+	patterns := map[string]bool{}
+	for _, j := range t.Jobs {
+		if j.shellFile == "" {
+			continue
+		}
+		keep[j.shellFile] = true
+		ext := filepath.Ext(j.shellFile)
+		prefix := strings.TrimSuffix(filepath.Base(j.shellFile), fmt.Sprintf("%d%s", j.id, ext))
+		patterns[filepath.Join(filepath.Dir(j.shellFile), prefix+"*"+ext)] = true
+	}
+	t.jobsMu.RUnlock()
+
+	for pattern := range patterns {
+		root := ""
+		if t.InDocker() {
+			root = t.rootPathInDocker
+		}
+		files, _ := filepath.Glob(filepath.Join(root, pattern))
+		for _, file := range files {
+			if keep[strings.TrimPrefix(file, filepath.Clean(root))] || keep[file] {
+				continue
+			}
+			if err := os.Remove(file); err == nil {
+				t.logger.Info("stale shell file removed", "file", file)
+			}
+		}
+	}
+}
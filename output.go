@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+const defaultOutputLimit = 256 << 10
+
+// outputBuffer captures the combined stdout and stderr of a run, only the latest limit bytes are kept.
+type outputBuffer struct {
+	mu        sync.Mutex
+	limit     int
+	data      []byte
+	truncated bool
+}
+
+// the buffers of the runs, reused by the next runs
+var outputBuffers = sync.Pool{New: func() any { return newOutputBuffer(defaultOutputLimit) }}
+
+func newOutputBuffer(limit int) *outputBuffer {
+	if limit <= 0 {
+		limit = defaultOutputLimit
+	}
+	return &outputBuffer{limit: limit}
+}
+
+// getOutputBuffer returns an empty buffer of the default limit from the pool, release it after the run
+func getOutputBuffer() *outputBuffer {
+	return outputBuffers.Get().(*outputBuffer)
+}
+
+// release puts the buffer back to the pool, it must not be used after released
+func (b *outputBuffer) release() {
+	b.mu.Lock()
+	b.data = b.data[:0]
+	b.truncated = false
+	b.mu.Unlock()
+	outputBuffers.Put(b)
+}
+
+func (b *outputBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.data = append(b.data, p...)
+	if over := len(b.data) - b.limit; over > 0 {
+		b.data = append(b.data[:0], b.data[over:]...)
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+func (b *outputBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.truncated {
+		return "...(truncated)\n" + string(b.data)
+	}
+	return string(b.data)
+}
+
+func (b *outputBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.data)
+}
+
+// tail returns the last n bytes at most, cut on a line boundary if possible
+func (b *outputBuffer) tail(n int) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	data := b.data
+	if len(data) > n {
+		data = data[len(data)-n:]
+		if i := bytes.IndexByte(data, '\n'); i >= 0 && i < len(data)-1 {
+			data = data[i+1:]
+		}
+	}
+	return strings.TrimRight(string(data), "\n")
+}
+
+// tailLines returns the last n lines, which are at most maxBytes
+func (b *outputBuffer) tailLines(n, maxBytes int) string {
+	if n <= 0 {
+		return ""
+	}
+
+	text := b.tail(maxBytes)
+	lines := strings.Split(text, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
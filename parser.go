@@ -7,6 +7,7 @@ import (
 	"gopkg.in/yaml.v3"
 	"os"
 	"strings"
+	"text/template"
 )
 
 func (t *Task) parseArguments(args []string) ([]*job, error) {
@@ -101,6 +102,7 @@ func dropCR(data []byte) []byte {
 
 func (t *Task) parseYamlFile(filePath string) ([]*job, error) {
 	type yamlConfig struct {
+		settings  `yaml:",inline"`
 		Schedules []*job `json:"schedules" yaml:"schedules"`
 	}
 	var actual yamlConfig
@@ -111,5 +113,27 @@ func (t *Task) parseYamlFile(filePath string) ([]*job, error) {
 			return nil, fmt.Errorf("unmarshal yaml file \"%s\" error: %w", filePath, err)
 		}
 	}
+	if tpl := actual.MessageTemplate; tpl != "" {
+		if _, err := template.New("message").Funcs(templateFuncs).Parse(tpl); err != nil {
+			return nil, fmt.Errorf("message_template of \"%s\" error: %w", filePath, err)
+		}
+	}
+	if lock := actual.LeaderElection.Lock; lock != "" && !containsString(leaderBackends, lock) {
+		return nil, fmt.Errorf("leader_election.lock of \"%s\" must be %v, yours: %s", filePath, leaderBackends, lock)
+	}
+	for name, schedule := range actual.ScheduleAliases {
+		if name == "" || strings.ContainsAny(name, " \t@") || schedule == "" {
+			return nil, fmt.Errorf("schedule_aliases of \"%s\" error: invalid alias %s: %s", filePath, name, schedule)
+		}
+	}
+	if err := actual.Docker.validate(); err != nil {
+		return nil, fmt.Errorf("docker of \"%s\" error: %w", filePath, err)
+	}
+	for i := range actual.Webhooks {
+		if err := actual.Webhooks[i].parse(); err != nil {
+			return nil, fmt.Errorf("webhooks[%d] of \"%s\" error: %w", i, filePath, err)
+		}
+	}
+	t.mergeSettings(actual.settings)
 	return actual.Schedules, nil
 }
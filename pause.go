@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+func (t *Task) isPaused() bool {
+	return atomic.LoadInt32(&t.paused) == 1
+}
+
+// Pause skips all firings until resumed, the jobs and the running commands are kept.
+func (t *Task) Pause(actor, source string) error {
+	if !atomic.CompareAndSwapInt32(&t.paused, 0, 1) {
+		return fmt.Errorf("already paused")
+	}
+	t.audit.record("pause", actor, source, "", "")
+	t.logger.Info("cron paused", "actor", actor, "source", source)
+	t.metrics.set("cron_paused", 1)
+	t.events.publish(eventPause, "", nil)
+	return nil
+}
+
+func (t *Task) Resume(actor, source string) error {
+	if !atomic.CompareAndSwapInt32(&t.paused, 1, 0) {
+		return fmt.Errorf("not paused")
+	}
+	t.audit.record("resume", actor, source, "", "")
+	t.logger.Info("cron resumed", "actor", actor, "source", source)
+	t.metrics.set("cron_paused", 0)
+	t.events.publish(eventResume, "", nil)
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	pingStart   = "start"
+	pingSuccess = "success"
+	pingFail    = "fail"
+
+	pingTimeout = 10 * time.Second
+)
+
+var pingClient = &http.Client{Timeout: pingTimeout}
+
+// pingURL builds the url for the monitoring services.
+//
+//	healthchecks(default): <ping_url>/start, <ping_url>, <ping_url>/fail
+//	cronitor: <ping_url>?state=run, <ping_url>?state=complete, <ping_url>?state=fail
+func (job *job) pingURL(state string) (string, error) {
+	switch strings.ToLower(job.PingStyle) {
+	case "", "healthchecks":
+		base := strings.TrimRight(job.PingURL, "/")
+		switch state {
+		case pingStart:
+			return base + "/start", nil
+		case pingFail:
+			return base + "/fail", nil
+		}
+		return base, nil
+	case "cronitor":
+		u, err := url.Parse(job.PingURL)
+		if err != nil {
+			return "", err
+		}
+		q := u.Query()
+		switch state {
+		case pingStart:
+			q.Set("state", "run")
+		case pingSuccess:
+			q.Set("state", "complete")
+		case pingFail:
+			q.Set("state", "fail")
+		}
+		u.RawQuery = q.Encode()
+		return u.String(), nil
+	}
+	return "", fmt.Errorf("invalid ping_style: %s", job.PingStyle)
+}
+
+func (job *job) ping(log *logger, state string) {
+	u, err := job.pingURL(state)
+	if err != nil {
+		log.Error(err, "ping fail", "name", job.Name, "state", state)
+		return
+	}
+
+	resp, err := pingClient.Get(u)
+	if err != nil {
+		log.Error(redactError(err), "ping fail", "name", job.Name, "state", state)
+		return
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		log.Error(fmt.Errorf("status code: %d", resp.StatusCode), "ping fail", "name", job.Name, "state", state)
+	}
+}
+
+// startPing sends the start ping in background, returns a function to send the result ping later.
+// The result ping is always sent after the start ping, so the monitoring services never receive them disordered.
+func (job *job) startPing(log *logger) func(success bool) {
+	if job.PingURL == "" {
+		return func(bool) {}
+	}
+
+	started := make(chan struct{})
+	job.task.goBackground(func() {
+		defer close(started)
+		job.ping(log, pingStart)
+	})
+
+	return func(success bool) {
+		job.task.goBackground(func() {
+			<-started
+			if success {
+				job.ping(log, pingSuccess)
+			} else {
+				job.ping(log, pingFail)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+//go:build !windows
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// processRunning returns true if the process is alive and runs the shell file, the pid may be reused by others.
+// The command line is checked via /proc, only the liveness without procfs, like macOS.
+func processRunning(pid int, shellFile string) bool {
+	if err := syscall.Kill(pid, 0); err != nil && !errors.Is(err, syscall.EPERM) {
+		return false
+	}
+	cmdline, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil {
+		return !errors.Is(err, os.ErrNotExist) || !procfsMounted()
+	}
+	return bytes.Contains(cmdline, []byte(shellFile))
+}
+
+func procfsMounted() bool {
+	_, err := os.Stat("/proc/self")
+	return err == nil
+}
+
+// setProcessGroup starts the command in its own process group, which is signaled by terminate and killProcess
+// with the children of the shell, and not the ctrl+c of the terminal, which is for cron to stop gracefully
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// terminate sends SIGTERM to the process group of the process,
+// or the process only if it is not a group leader, like the orphans of the versions before
+func terminate(process *os.Process) error {
+	return signalGroup(process, syscall.SIGTERM)
+}
+
+// killProcess kills the process group of the process
+func killProcess(process *os.Process) error {
+	return signalGroup(process, syscall.SIGKILL)
+}
+
+func signalGroup(process *os.Process, sig syscall.Signal) error {
+	if err := syscall.Kill(-process.Pid, sig); !errors.Is(err, syscall.ESRCH) {
+		return err
+	}
+	return process.Signal(sig)
+}
@@ -0,0 +1,41 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+var generateConsoleCtrlEvent = syscall.NewLazyDLL("kernel32.dll").NewProc("GenerateConsoleCtrlEvent")
+
+// processRunning returns true if the process is alive, the command line is not checked on windows.
+func processRunning(pid int, shellFile string) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = process.Release()
+	return true
+}
+
+// setProcessGroup starts the command in its own process group, which receives ctrl+break by terminate,
+// and not the ctrl+c of the console, which is for cron to stop gracefully
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// terminate sends ctrl+break to the process group of the process, there are no signals on windows.
+// It fails if the process is not attached to the console of cron, like the orphans of the last instance
+func terminate(process *os.Process) error {
+	if r, _, err := generateConsoleCtrlEvent.Call(syscall.CTRL_BREAK_EVENT, uintptr(process.Pid)); r == 0 {
+		return err
+	}
+	return nil
+}
+
+// killProcess kills the process, the children are not killed on windows
+func killProcess(process *os.Process) error {
+	return process.Kill()
+}
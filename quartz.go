@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// quartzSchedule supports the day specifiers of Quartz, which the cron parser does not:
+//
+//	day-of-month: L (the last day), L-3 (the 3rd day before the last day), LW (the last weekday), 15W (the nearest weekday to the 15th)
+//	day-of-week:  5L or FRIL (the last friday), 5#3 or FRI#3 (the 3rd friday), 0-6 are SUN-SAT as the cron parser
+//
+// The other items of the fields are kept, like "1,L" or "MON#1,FRIL"
+type quartzSchedule struct {
+	spec *cron.SpecSchedule // the schedule of every day
+	days *quartzDays
+}
+
+type quartzDays struct {
+	dom, dow         uint64 // the bits of the plain items
+	domStar, dowStar bool
+
+	last           []int // L, L-n: the days before the last day
+	lastWeekday    bool  // LW
+	nearestWeekday []int // nW
+	lastDow        []time.Weekday
+	nthDow         map[time.Weekday][]int
+}
+
+// parseQuartz parses the schedule with the Quartz day specifiers, returns nil if none
+func parseQuartz(expr string) (cron.Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) > 0 && (strings.HasPrefix(fields[0], "TZ=") || strings.HasPrefix(fields[0], "CRON_TZ=")) {
+		fields = fields[1:]
+	}
+	if len(fields) < 5 || strings.HasPrefix(fields[0], "@") {
+		return nil, nil
+	}
+
+	dom, dow := fields[len(fields)-3], fields[len(fields)-1]
+	if !strings.ContainsAny(strings.ToUpper(dom), "LW") && !hasQuartzDow(dow) {
+		return nil, nil
+	}
+
+	days, err := parseQuartzDays(dom, dow)
+	if err != nil {
+		return nil, err
+	}
+
+	// every day, filtered by the days
+	daily := strings.Fields(expr)
+	daily[len(daily)-3], daily[len(daily)-1] = "*", "*"
+	schedule, err := cronParser.Parse(strings.Join(daily, " "))
+	if err != nil {
+		return nil, err
+	}
+	return &quartzSchedule{spec: schedule.(*cron.SpecSchedule), days: days}, nil
+}
+
+// hasQuartzDow returns true if any item of the day-of-week is nL or n#m, like "MON,FRIL"
+func hasQuartzDow(dow string) bool {
+	for _, item := range strings.Split(strings.ToUpper(dow), ",") {
+		if strings.Contains(item, "#") || strings.HasSuffix(item, "L") {
+			return true
+		}
+	}
+	return false
+}
+
+func parseQuartzDays(dom, dow string) (*quartzDays, error) {
+	days := &quartzDays{domStar: dom == "*" || dom == "?", dowStar: dow == "*" || dow == "?", nthDow: map[time.Weekday][]int{}}
+
+	var plain []string
+	for _, item := range strings.Split(strings.ToUpper(dom), ",") {
+		switch {
+		case item == "LW":
+			days.lastWeekday = true
+		case item == "L":
+			days.last = append(days.last, 0)
+		case strings.HasPrefix(item, "L-"):
+			n, err := strconv.Atoi(item[2:])
+			if err != nil || n < 0 || n > 30 {
+				return nil, fmt.Errorf("invalid day-of-month %s, must be L-[0-30]", item)
+			}
+			days.last = append(days.last, n)
+		case strings.HasSuffix(item, "W"):
+			n, err := strconv.Atoi(item[:len(item)-1])
+			if err != nil || n < 1 || n > 31 {
+				return nil, fmt.Errorf("invalid day-of-month %s, must be [1-31]W", item)
+			}
+			days.nearestWeekday = append(days.nearestWeekday, n)
+		default:
+			plain = append(plain, item)
+		}
+	}
+	if len(plain) > 0 {
+		spec, err := cronParser.Parse("0 0 0 " + strings.Join(plain, ",") + " * *")
+		if err != nil {
+			return nil, err
+		}
+		days.dom = spec.(*cron.SpecSchedule).Dom
+	}
+
+	plain = nil
+	for _, item := range strings.Split(strings.ToUpper(dow), ",") {
+		if weekday, nth, ok := strings.Cut(item, "#"); ok {
+			d, err := parseWeekday(weekday)
+			if err != nil {
+				return nil, err
+			}
+			n, err := strconv.Atoi(nth)
+			if err != nil || n < 1 || n > 5 {
+				return nil, fmt.Errorf("invalid day-of-week %s, must be <weekday>#[1-5]", item)
+			}
+			days.nthDow[d] = append(days.nthDow[d], n)
+		} else if len(item) > 1 && strings.HasSuffix(item, "L") {
+			d, err := parseWeekday(item[:len(item)-1])
+			if err != nil {
+				return nil, err
+			}
+			days.lastDow = append(days.lastDow, d)
+		} else {
+			plain = append(plain, item)
+		}
+	}
+	if len(plain) > 0 {
+		spec, err := cronParser.Parse("0 0 0 * * " + strings.Join(plain, ","))
+		if err != nil {
+			return nil, err
+		}
+		days.dow = spec.(*cron.SpecSchedule).Dow
+	}
+	return days, nil
+}
+
+// parseWeekday parses a single day-of-week, like 5 or FRI
+func parseWeekday(s string) (time.Weekday, error) {
+	spec, err := cronParser.Parse("0 0 0 * * " + s)
+	if err != nil {
+		return 0, err
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if dow := spec.(*cron.SpecSchedule).Dow; dow == 1<<uint(d) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid day-of-week %s, must be a single day", s)
+}
+
+func (s *quartzSchedule) Next(t time.Time) time.Time {
+	loc := s.spec.Location
+	if loc == time.Local {
+		loc = t.Location()
+	}
+
+	// the cron parser gives up after 5 years too
+	for limit := t.AddDate(5, 0, 0); t.Before(limit); {
+		next := s.spec.Next(t)
+		if next.IsZero() {
+			return next
+		}
+		if s.days.match(next.In(loc)) {
+			return next
+		}
+		// the last second of the day, then the first firing of the next day
+		y, m, d := next.In(loc).Date()
+		t = time.Date(y, m, d+1, 0, 0, 0, 0, loc).Add(-time.Second)
+	}
+	return time.Time{}
+}
+
+// match returns true if the day matches, both fields must match if one is *, like the cron parser
+func (d *quartzDays) match(t time.Time) bool {
+	if d.domStar || d.dowStar {
+		return (d.domStar || d.matchDom(t)) && (d.dowStar || d.matchDow(t))
+	}
+	return d.matchDom(t) || d.matchDow(t)
+}
+
+func (d *quartzDays) matchDom(t time.Time) bool {
+	day, last := t.Day(), daysIn(t)
+	if d.dom&(1<<uint(day)) != 0 {
+		return true
+	}
+	for _, n := range d.last {
+		if day == last-n {
+			return true
+		}
+	}
+	if d.lastWeekday && day == nearestWeekday(t, last) {
+		return true
+	}
+	for _, n := range d.nearestWeekday {
+		if n <= last && day == nearestWeekday(t, n) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *quartzDays) matchDow(t time.Time) bool {
+	weekday := t.Weekday()
+	if d.dow&(1<<uint(weekday)) != 0 {
+		return true
+	}
+	for _, w := range d.lastDow {
+		if weekday == w && t.Day()+7 > daysIn(t) {
+			return true
+		}
+	}
+	for _, n := range d.nthDow[weekday] {
+		if (t.Day()-1)/7+1 == n {
+			return true
+		}
+	}
+	return false
+}
+
+// daysIn returns the number of days in the month of t
+func daysIn(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// nearestWeekday returns the weekday nearest to the day of the month of t, without crossing the month
+func nearestWeekday(t time.Time, day int) int {
+	last := daysIn(t)
+	switch time.Date(t.Year(), t.Month(), day, 0, 0, 0, 0, time.UTC).Weekday() {
+	case time.Saturday:
+		if day == 1 {
+			return day + 2
+		}
+		return day - 1
+	case time.Sunday:
+		if day == last {
+			return day - 2
+		}
+		return day + 1
+	}
+	return day
+}
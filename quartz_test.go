@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuartzNext(t *testing.T) {
+	tests := []struct {
+		expr string
+		from string
+		want string
+	}{
+		{"0 0 L * *", "2024-02-10T00:00:00Z", "2024-02-29T00:00:00Z"}, // leap year
+		{"0 0 L * *", "2023-02-10T00:00:00Z", "2023-02-28T00:00:00Z"},
+		{"0 0 L * *", "2024-12-31T00:00:00Z", "2025-01-31T00:00:00Z"}, // after the last day of the year
+		{"0 0 L-2 * *", "2024-04-01T00:00:00Z", "2024-04-28T00:00:00Z"},
+		{"0 0 1,L * *", "2024-04-02T00:00:00Z", "2024-04-30T00:00:00Z"},
+		{"0 0 LW * *", "2024-03-01T00:00:00Z", "2024-03-29T00:00:00Z"},  // the 31st is sunday
+		{"0 0 15W * *", "2024-06-01T00:00:00Z", "2024-06-14T00:00:00Z"}, // the 15th is saturday
+		{"0 0 1W * *", "2024-05-31T12:00:00Z", "2024-06-03T00:00:00Z"},  // the 1st is saturday, not back to may
+		{"0 0 31W * *", "2024-04-01T00:00:00Z", "2024-05-31T00:00:00Z"}, // no 31st in april
+		{"0 0 * * 5L", "2024-05-01T00:00:00Z", "2024-05-31T00:00:00Z"},
+		{"0 0 * * FRI#3", "2024-05-01T00:00:00Z", "2024-05-17T00:00:00Z"},
+		{"0 0 * * 5L,1", "2024-05-01T00:00:00Z", "2024-05-06T00:00:00Z"},
+		{"0 0 * * FRIL,MON", "2024-05-28T00:00:00Z", "2024-05-31T00:00:00Z"},
+		{"0 0 * * MON#1,FRIL", "2024-05-07T00:00:00Z", "2024-05-31T00:00:00Z"},
+		{"30 2 L * MON#1", "2024-05-07T00:00:00Z", "2024-05-31T02:30:00Z"}, // either of the days
+		{"TZ=Asia/Shanghai 0 9 L * *", "2024-01-31T02:00:00Z", "2024-02-29T01:00:00Z"},
+	}
+	for _, test := range tests {
+		t.Run(test.expr+" from "+test.from, func(t *testing.T) {
+			schedule, err := parseQuartz(test.expr)
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			} else if schedule == nil {
+				t.Fatalf("not a quartz schedule")
+			}
+			from, _ := time.Parse(time.RFC3339, test.from)
+			if got := schedule.Next(from).UTC().Format(time.RFC3339); got != test.want {
+				t.Errorf("Next() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseQuartz(t *testing.T) {
+	tests := []struct {
+		expr    string
+		quartz  bool
+		wantErr bool
+	}{
+		{"0 0 * * 1", false, false},
+		{"0 0 1-5 * MON-FRI", false, false},
+		{"@daily", false, false},
+		{"0 0 ? * 5L", true, false},
+		{"0 0 * * 5L,1", true, false},
+		{"0 0 * * 1,FRI#2", true, false},
+		{"0 0 L-31 * *", true, true},
+		{"0 0 32W * *", true, true},
+		{"0 0 * * FRI#6", true, true},
+		{"0 0 * * 1-5L", true, true},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			schedule, err := parseQuartz(test.expr)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error = %v, want error %v", err, test.wantErr)
+			}
+			if !test.wantErr && (schedule != nil) != test.quartz {
+				t.Errorf("quartz = %v, want %v", schedule != nil, test.quartz)
+			}
+		})
+	}
+}
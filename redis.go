@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const redisTimeout = 5 * time.Second
+
+type redisSettings struct {
+	Addr     string `json:"addr" yaml:"addr"` // host:port
+	Password string `json:"password" yaml:"password"`
+	DB       int    `json:"db" yaml:"db"`
+}
+
+// redisConn is a minimal client of the RESP protocol, enough for the locks
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialRedis(settings redisSettings) (*redisConn, error) {
+	conn, err := net.DialTimeout("tcp", settings.Addr, redisTimeout)
+	if err != nil {
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Now().Add(redisTimeout))
+
+	c := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+	if settings.Password != "" {
+		if _, err = c.do("AUTH", settings.Password); err != nil {
+			c.close()
+			return nil, err
+		}
+	}
+	if settings.DB > 0 {
+		if _, err = c.do("SELECT", strconv.Itoa(settings.DB)); err != nil {
+			c.close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *redisConn) close() {
+	_ = c.conn.Close()
+}
+
+// do sends the command, returns the reply: string for simple/bulk strings, int64 for integers,
+// []any for arrays, nil for null
+func (c *redisConn) do(args ...string) (any, error) {
+	var b strings.Builder
+	b.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		b.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n")
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+func (c *redisConn) readReply() (any, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if len(line) <= 0 {
+		return nil, fmt.Errorf("invalid redis reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, fmt.Errorf("redis error: %s", line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err = io.ReadFull(c.reader, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = c.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("invalid redis reply: %s", line)
+}
+
+// redisLocker locks by SET NX PX, unlocks by a script comparing the token
+type redisLocker struct {
+	settings redisSettings
+}
+
+const redisUnlockScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then
+	if tonumber(ARGV[2]) > 0 then
+		return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	end
+	return redis.call("DEL", KEYS[1])
+end
+return 0`
+
+const redisRenewScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`
+
+func newRedisLocker(settings redisSettings) *redisLocker {
+	return &redisLocker{settings: settings}
+}
+
+func (r *redisLocker) String() string {
+	return "redis " + r.settings.Addr
+}
+
+func (r *redisLocker) lock(key, token string, ttl time.Duration) (bool, error) {
+	c, err := dialRedis(r.settings)
+	if err != nil {
+		return false, err
+	}
+	defer c.close()
+
+	reply, err := c.do("SET", key, token, "NX", "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	if err != nil {
+		return false, err
+	}
+	return reply == "OK", nil
+}
+
+func (r *redisLocker) unlock(key, token string, keep time.Duration) error {
+	c, err := dialRedis(r.settings)
+	if err != nil {
+		return err
+	}
+	defer c.close()
+
+	if keep < 0 {
+		keep = 0
+	}
+	_, err = c.do("EVAL", redisUnlockScript, "1", key, token, strconv.FormatInt(keep.Milliseconds(), 10))
+	return err
+}
+
+func (r *redisLocker) renew(key, token string, ttl time.Duration) (bool, error) {
+	c, err := dialRedis(r.settings)
+	if err != nil {
+		return false, err
+	}
+	defer c.close()
+
+	reply, err := c.do("EVAL", redisRenewScript, "1", key, token, strconv.FormatInt(ttl.Milliseconds(), 10))
+	if err != nil {
+		return false, err
+	}
+	return reply == int64(1), nil
+}
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the frequencies of RRULE, the periods of the occurrences
+var rruleFrequencies = []string{"YEARLY", "MONTHLY", "WEEKLY", "DAILY", "HOURLY", "MINUTELY"}
+
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// rruleSchedule is the recurrence rule of RFC 5545, like
+//
+//	DTSTART;TZID=Asia/Shanghai:20260101T090000
+//	RRULE:FREQ=MONTHLY;INTERVAL=3;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-2
+//
+// DTSTART is 00:00:00 of the loading day by default, and required by INTERVAL and COUNT, which count from it.
+// The times are floating (local) without Z or TZID.
+// BYYEARDAY, BYWEEKNO and SECONDLY are not supported.
+type rruleSchedule struct {
+	start    time.Time
+	freq     string
+	interval int
+	count    int
+	until    time.Time
+	wkst     time.Weekday
+
+	byMonth    []int
+	byMonthDay []int
+	byDay      []rruleDay
+	byHour     []int
+	byMinute   []int
+	bySecond   []int
+	bySetPos   []int
+}
+
+// rruleDay is the item of BYDAY, like MO, 1FR or -1SU
+type rruleDay struct {
+	nth     int // 0 is every weekday of the period
+	weekday time.Weekday
+}
+
+// parseRRule parses the schedule of RRULE, returns nil if not a RRULE
+func parseRRule(expr string) (*rruleSchedule, error) {
+	if !strings.Contains(strings.ToUpper(expr), "FREQ=") {
+		return nil, nil
+	}
+
+	y, m, d := time.Now().Date()
+	s := &rruleSchedule{start: time.Date(y, m, d, 0, 0, 0, 0, time.Local), interval: 1, wkst: time.Monday}
+	var rule string
+	var started bool
+	for _, line := range strings.Fields(expr) {
+		upper := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(upper, "DTSTART"):
+			start, err := parseRRuleTime(line[len("DTSTART"):])
+			if err != nil {
+				return nil, fmt.Errorf("invalid DTSTART: %w", err)
+			}
+			s.start, started = start, true
+		case strings.HasPrefix(upper, "RRULE:"):
+			rule = line[len("RRULE:"):]
+		case strings.HasPrefix(upper, "FREQ="):
+			rule = line
+		default:
+			return nil, fmt.Errorf("invalid line of RRULE: %s", line)
+		}
+	}
+
+	var err error
+	for _, part := range strings.Split(rule, ";") {
+		key, value, _ := strings.Cut(part, "=")
+		value = strings.ToUpper(value)
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			if !containsString(rruleFrequencies, value) {
+				return nil, fmt.Errorf("FREQ must be %v, yours: %s", rruleFrequencies, value)
+			}
+			s.freq = value
+		case "INTERVAL":
+			if s.interval, err = strconv.Atoi(value); err != nil || s.interval < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL: %s", value)
+			}
+		case "COUNT":
+			if s.count, err = strconv.Atoi(value); err != nil || s.count < 1 {
+				return nil, fmt.Errorf("invalid COUNT: %s", value)
+			}
+		case "UNTIL":
+			if s.until, err = parseRRuleTime(":" + value); err != nil {
+				return nil, fmt.Errorf("invalid UNTIL: %w", err)
+			}
+		case "WKST":
+			weekday, ok := rruleWeekdays[value]
+			if !ok {
+				return nil, fmt.Errorf("invalid WKST: %s", value)
+			}
+			s.wkst = weekday
+		case "BYMONTH":
+			s.byMonth, err = parseRRuleInts(key, value, 1, 12, false)
+		case "BYMONTHDAY":
+			s.byMonthDay, err = parseRRuleInts(key, value, 1, 31, true)
+		case "BYHOUR":
+			s.byHour, err = parseRRuleInts(key, value, 0, 23, false)
+		case "BYMINUTE":
+			s.byMinute, err = parseRRuleInts(key, value, 0, 59, false)
+		case "BYSECOND":
+			s.bySecond, err = parseRRuleInts(key, value, 0, 59, false)
+		case "BYSETPOS":
+			s.bySetPos, err = parseRRuleInts(key, value, 1, 366, true)
+		case "BYDAY":
+			for _, item := range strings.Split(value, ",") {
+				if len(item) < 2 {
+					return nil, fmt.Errorf("invalid BYDAY: %s", item)
+				}
+				weekday, ok := rruleWeekdays[item[len(item)-2:]]
+				if !ok {
+					return nil, fmt.Errorf("invalid BYDAY: %s", item)
+				}
+				day := rruleDay{weekday: weekday}
+				if nth := item[:len(item)-2]; nth != "" {
+					if day.nth, err = strconv.Atoi(nth); err != nil || day.nth == 0 || day.nth > 53 || day.nth < -53 {
+						return nil, fmt.Errorf("invalid BYDAY: %s", item)
+					}
+				}
+				s.byDay = append(s.byDay, day)
+			}
+		default:
+			return nil, fmt.Errorf("%s of RRULE not supported", key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if s.freq == "" {
+		return nil, fmt.Errorf("FREQ of RRULE required")
+	} else if !started && (s.interval > 1 || s.count > 0) {
+		// the occurrences would shift with the day of loading
+		return nil, fmt.Errorf("DTSTART of RRULE required with INTERVAL or COUNT")
+	}
+	return s, nil
+}
+
+// parseRRuleTime parses the value of DTSTART or UNTIL, like :20260101T090000Z, ;TZID=Asia/Shanghai:20260101T090000 or ;VALUE=DATE:20260101
+func parseRRuleTime(s string) (time.Time, error) {
+	params, value, ok := strings.Cut(s, ":")
+	if !ok {
+		return time.Time{}, fmt.Errorf("%s must be like :20260101T090000", s)
+	}
+
+	loc := time.Local
+	for _, param := range strings.Split(params, ";") {
+		if key, tz, _ := strings.Cut(param, "="); strings.EqualFold(key, "TZID") {
+			l, err := time.LoadLocation(tz)
+			if err != nil {
+				return time.Time{}, err
+			}
+			loc = l
+		}
+	}
+	if strings.HasSuffix(value, "Z") {
+		value, loc = strings.TrimSuffix(value, "Z"), time.UTC
+	}
+	if len(value) == len("20060102") {
+		return time.ParseInLocation("20060102", value, loc)
+	}
+	return time.ParseInLocation("20060102T150405", value, loc)
+}
+
+// parseRRuleInts parses the list of integers within [min, max], or [-max, -min] too if negative
+func parseRRuleInts(key, value string, min, max int, negative bool) ([]int, error) {
+	var values []int
+	for _, item := range strings.Split(value, ",") {
+		v, err := strconv.Atoi(item)
+		abs := v
+		if negative && abs < 0 {
+			abs = -abs
+		}
+		if err != nil || abs < min || abs > max {
+			return nil, fmt.Errorf("invalid %s: %s", key, item)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func (s *rruleSchedule) Next(t time.Time) time.Time {
+	limit := t.AddDate(5, 0, 0)
+
+	// the occurrences are counted from DTSTART
+	k := 0
+	if s.count == 0 {
+		if k = s.periodIndex(t.In(s.start.Location())); k > 0 {
+			k -= k % s.interval
+		} else {
+			k = 0
+		}
+	}
+
+	n := 0
+	for ; ; k += s.interval {
+		period := s.periodStart(k)
+		if period.After(limit) || (!s.until.IsZero() && period.After(s.until)) {
+			return time.Time{}
+		}
+		for _, occurrence := range s.occurrences(period) {
+			if occurrence.Before(s.start) {
+				continue
+			}
+			if !s.until.IsZero() && occurrence.After(s.until) {
+				return time.Time{}
+			}
+			if n++; s.count > 0 && n > s.count {
+				return time.Time{}
+			}
+			if occurrence.After(t) {
+				return occurrence.In(t.Location())
+			}
+		}
+	}
+}
+
+// periodStart returns the start of the kth period from DTSTART
+func (s *rruleSchedule) periodStart(k int) time.Time {
+	start := s.start
+	y, m, d := start.Date()
+	loc := start.Location()
+	switch s.freq {
+	case "YEARLY":
+		return time.Date(y+k, 1, 1, 0, 0, 0, 0, loc)
+	case "MONTHLY":
+		return time.Date(y, m+time.Month(k), 1, 0, 0, 0, 0, loc)
+	case "WEEKLY":
+		offset := (int(start.Weekday()) - int(s.wkst) + 7) % 7
+		return time.Date(y, m, d-offset+7*k, 0, 0, 0, 0, loc)
+	case "DAILY":
+		return time.Date(y, m, d+k, 0, 0, 0, 0, loc)
+	case "HOURLY":
+		return time.Date(y, m, d, start.Hour()+k, 0, 0, 0, loc)
+	default:
+		return time.Date(y, m, d, start.Hour(), start.Minute()+k, 0, 0, loc)
+	}
+}
+
+// periodIndex returns the index of the period of t from DTSTART
+func (s *rruleSchedule) periodIndex(t time.Time) int {
+	days := func(from, to time.Time) int {
+		fy, fm, fd := from.Date()
+		ty, tm, td := to.Date()
+		return int(time.Date(ty, tm, td, 0, 0, 0, 0, time.UTC).Sub(time.Date(fy, fm, fd, 0, 0, 0, 0, time.UTC)).Hours() / 24)
+	}
+
+	switch s.freq {
+	case "YEARLY":
+		return t.Year() - s.start.Year()
+	case "MONTHLY":
+		return (t.Year()-s.start.Year())*12 + int(t.Month()) - int(s.start.Month())
+	case "WEEKLY":
+		return days(s.periodStart(0), t) / 7
+	case "DAILY":
+		return days(s.start, t)
+	case "HOURLY":
+		return int(t.Sub(s.periodStart(0)) / time.Hour)
+	default:
+		return int(t.Sub(s.periodStart(0)) / time.Minute)
+	}
+}
+
+// occurrences returns the sorted occurrences within the period
+func (s *rruleSchedule) occurrences(period time.Time) []time.Time {
+	var occurrences []time.Time
+	for _, day := range s.days(period) {
+		for _, hour := range s.expand(s.byHour, s.start.Hour(), period.Hour(), s.freq == "HOURLY" || s.freq == "MINUTELY") {
+			for _, minute := range s.expand(s.byMinute, s.start.Minute(), period.Minute(), s.freq == "MINUTELY") {
+				for _, second := range s.expand(s.bySecond, s.start.Second(), 0, false) {
+					y, m, d := day.Date()
+					occurrences = append(occurrences, rruleTime(y, m, d, hour, minute, second, day.Location()))
+				}
+			}
+		}
+	}
+	sort.Slice(occurrences, func(i, j int) bool { return occurrences[i].Before(occurrences[j]) })
+
+	if len(s.bySetPos) <= 0 {
+		return occurrences
+	}
+	var selected []time.Time
+	for _, pos := range s.bySetPos {
+		if pos > 0 && pos <= len(occurrences) {
+			selected = append(selected, occurrences[pos-1])
+		} else if pos < 0 && -pos <= len(occurrences) {
+			selected = append(selected, occurrences[len(occurrences)+pos])
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].Before(selected[j]) })
+	return selected
+}
+
+// rruleTime returns the wall time in loc, the ones skipped by DST are by the offset before the transition
+// like RFC 5545, 02:30 => 03:30
+func rruleTime(y int, m time.Month, d, hour, minute, second int, loc *time.Location) time.Time {
+	t := time.Date(y, m, d, hour, minute, second, 0, loc)
+	if t.Hour() == hour && t.Minute() == minute && t.Second() == second {
+		return t
+	}
+	_, before := t.Add(-12 * time.Hour).Zone()
+	return time.Date(y, m, d, hour, minute, second, 0, time.UTC).Add(-time.Duration(before) * time.Second).In(loc)
+}
+
+// expand returns the values of BYHOUR, BYMINUTE or BYSECOND, or the one of the period or DTSTART
+func (s *rruleSchedule) expand(by []int, start, period int, ofPeriod bool) []int {
+	if !ofPeriod {
+		if len(by) > 0 {
+			return by
+		}
+		return []int{start}
+	}
+	if len(by) > 0 && !containsInt(by, period) {
+		return nil
+	}
+	return []int{period}
+}
+
+// days returns the days of the occurrences within the period
+func (s *rruleSchedule) days(period time.Time) []time.Time {
+	y, m, d := period.Date()
+	loc := period.Location()
+
+	var days []time.Time
+	switch s.freq {
+	case "YEARLY":
+		if len(s.byMonth) <= 0 && len(s.byMonthDay) <= 0 && len(s.byDay) > 0 {
+			// the nth weekday of the year
+			total := time.Date(y, 12, 31, 0, 0, 0, 0, loc).YearDay()
+			for i := 1; i <= total; i++ {
+				if day := time.Date(y, 1, i, 0, 0, 0, 0, loc); s.matchWeekday(day, i, total) {
+					days = append(days, day)
+				}
+			}
+			return days
+		}
+		months := s.byMonth
+		if len(months) <= 0 {
+			months = []int{int(s.start.Month())}
+		}
+		for _, month := range months {
+			days = append(days, s.monthDays(y, time.Month(month), loc)...)
+		}
+	case "MONTHLY":
+		if len(s.byMonth) <= 0 || containsInt(s.byMonth, int(m)) {
+			days = s.monthDays(y, m, loc)
+		}
+	case "WEEKLY":
+		for i := 0; i < 7; i++ {
+			day := time.Date(y, m, d+i, 0, 0, 0, 0, loc)
+			if len(s.byMonth) > 0 && !containsInt(s.byMonth, int(day.Month())) {
+				continue
+			}
+			if (len(s.byDay) <= 0 && day.Weekday() == s.start.Weekday()) || (len(s.byDay) > 0 && s.matchWeekday(day, 0, 0)) {
+				days = append(days, day)
+			}
+		}
+	default:
+		day := time.Date(y, m, d, 0, 0, 0, 0, loc)
+		last := daysIn(day)
+		if (len(s.byMonth) <= 0 || containsInt(s.byMonth, int(m))) &&
+			(len(s.byMonthDay) <= 0 || s.matchMonthDay(d, last)) &&
+			(len(s.byDay) <= 0 || s.matchWeekday(day, 0, 0)) {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// monthDays returns the days of the month by BYMONTHDAY and BYDAY, or the day of DTSTART
+func (s *rruleSchedule) monthDays(y int, m time.Month, loc *time.Location) []time.Time {
+	last := daysIn(time.Date(y, m, 1, 0, 0, 0, 0, loc))
+	if len(s.byMonthDay) <= 0 && len(s.byDay) <= 0 {
+		if s.start.Day() > last {
+			return nil
+		}
+		return []time.Time{time.Date(y, m, s.start.Day(), 0, 0, 0, 0, loc)}
+	}
+
+	var days []time.Time
+	for i := 1; i <= last; i++ {
+		day := time.Date(y, m, i, 0, 0, 0, 0, loc)
+		if (len(s.byMonthDay) <= 0 || s.matchMonthDay(i, last)) && (len(s.byDay) <= 0 || s.matchWeekday(day, i, last)) {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+func (s *rruleSchedule) matchMonthDay(day, last int) bool {
+	for _, n := range s.byMonthDay {
+		if n == day || (n < 0 && last+n+1 == day) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWeekday matches BYDAY, the nth is the index of the day within the total days of the month or year, ignored if total is 0
+func (s *rruleSchedule) matchWeekday(day time.Time, index, total int) bool {
+	for _, by := range s.byDay {
+		if by.weekday != day.Weekday() {
+			continue
+		}
+		if by.nth == 0 || total == 0 ||
+			(by.nth > 0 && (index-1)/7+1 == by.nth) ||
+			(by.nth < 0 && (total-index)/7+1 == -by.nth) {
+			return true
+		}
+	}
+	return false
+}
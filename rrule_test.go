@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRRuleNext(t *testing.T) {
+	tests := []struct {
+		expr string
+		from string
+		want string // empty if no more occurrences
+	}{
+		// month ends
+		{"DTSTART:20240131T090000Z RRULE:FREQ=MONTHLY", "2024-01-31T09:00:00Z", "2024-03-31T09:00:00Z"}, // no 31st in february
+		{"DTSTART:20240101T090000Z RRULE:FREQ=MONTHLY;BYMONTHDAY=-1", "2024-02-01T00:00:00Z", "2024-02-29T09:00:00Z"},
+		{"DTSTART:20240101T090000Z RRULE:FREQ=MONTHLY;BYMONTHDAY=31", "2024-12-31T09:00:00Z", "2025-01-31T09:00:00Z"},
+		{"DTSTART:20240229T000000Z RRULE:FREQ=YEARLY", "2024-02-29T00:00:00Z", "2028-02-29T00:00:00Z"},
+
+		// BYSETPOS
+		{"DTSTART:20240101T090000Z RRULE:FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", "2024-03-01T00:00:00Z", "2024-03-29T09:00:00Z"},
+		{"DTSTART:20240101T090000Z RRULE:FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-2", "2024-06-01T00:00:00Z", "2024-06-27T09:00:00Z"},
+		{"DTSTART:20240101T090000Z RRULE:FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=1", "2024-06-01T00:00:00Z", "2024-06-03T09:00:00Z"},
+		{"DTSTART:20240101T090000Z RRULE:FREQ=MONTHLY;BYDAY=-1FR", "2024-05-01T00:00:00Z", "2024-05-31T09:00:00Z"},
+
+		// DST of America/New_York, 2024-03-10 02:00 and 2024-11-03 02:00
+		{"DTSTART;TZID=America/New_York:20240301T090000 RRULE:FREQ=DAILY", "2024-03-09T14:00:00Z", "2024-03-10T13:00:00Z"},
+		{"DTSTART;TZID=America/New_York:20240301T023000 RRULE:FREQ=DAILY", "2024-03-09T07:30:00Z", "2024-03-10T07:30:00Z"}, // 02:30 skipped, 03:30
+		{"DTSTART;TZID=America/New_York:20240301T023000 RRULE:FREQ=DAILY", "2024-03-10T07:30:00Z", "2024-03-11T06:30:00Z"},
+		{"DTSTART;TZID=America/New_York:20241101T013000 RRULE:FREQ=DAILY", "2024-11-02T05:30:00Z", "2024-11-03T05:30:00Z"}, // the first 01:30
+		{"DTSTART;TZID=America/New_York:20241101T013000 RRULE:FREQ=DAILY", "2024-11-03T05:30:00Z", "2024-11-04T06:30:00Z"},
+
+		// INTERVAL, COUNT and UNTIL
+		{"DTSTART:20240101T100000Z RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", "2024-01-06T00:00:00Z", "2024-01-15T10:00:00Z"},
+		{"DTSTART:20240101T000000Z RRULE:FREQ=DAILY;COUNT=3", "2024-01-02T12:00:00Z", "2024-01-03T00:00:00Z"},
+		{"DTSTART:20240101T000000Z RRULE:FREQ=DAILY;COUNT=3", "2024-01-03T00:00:00Z", ""},
+		{"DTSTART:20240101T100000Z RRULE:FREQ=DAILY;UNTIL=20240105T000000Z", "2024-01-05T00:00:00Z", ""},
+		{"DTSTART:20240101T000000Z RRULE:FREQ=HOURLY;INTERVAL=5", "2024-01-01T23:00:00Z", "2024-01-02T01:00:00Z"},
+	}
+	for _, test := range tests {
+		t.Run(test.expr+" from "+test.from, func(t *testing.T) {
+			schedule, err := parseRRule(test.expr)
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			from, _ := time.Parse(time.RFC3339, test.from)
+			var got string
+			if next := schedule.Next(from); !next.IsZero() {
+				got = next.UTC().Format(time.RFC3339)
+			}
+			if got != test.want {
+				t.Errorf("Next() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseRRule(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr bool
+	}{
+		{"FREQ=DAILY", false},
+		{"RRULE:FREQ=WEEKLY;BYDAY=MO", false},
+		{"DTSTART:20240101T000000Z RRULE:FREQ=DAILY;INTERVAL=2", false},
+		{"DTSTART;VALUE=DATE:20240101 RRULE:FREQ=DAILY;COUNT=3", false},
+		{"RRULE:FREQ=DAILY;INTERVAL=2", true}, // DTSTART required
+		{"FREQ=DAILY;COUNT=3", true},
+		{"FREQ=SECONDLY", true},
+		{"FREQ=DAILY;BYWEEKNO=1", true},
+		{"FREQ=MONTHLY;BYDAY=6MO", false},
+		{"FREQ=MONTHLY;BYDAY=54MO", true},
+		{"RRULE:INTERVAL=2", false}, // not a RRULE
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			if _, err := parseRRule(test.expr); (err != nil) != test.wantErr {
+				t.Errorf("error = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// runningJobs keeps the running jobs, the waiters are woken up once the last run is finished
+type runningJobs struct {
+	mu   sync.Mutex
+	seq  int64
+	runs map[int64]runningJob
+	idle chan struct{} // closed when no runs left
+}
+
+type runningJob struct {
+	Name      string
+	StartedAt time.Time
+}
+
+// add records a run of the job, returns the id for done
+func (r *runningJobs) add(name string) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.runs) == 0 {
+		r.runs = map[int64]runningJob{}
+		r.idle = make(chan struct{})
+	}
+	r.seq++
+	r.runs[r.seq] = runningJob{Name: name, StartedAt: time.Now()}
+	return r.seq
+}
+
+func (r *runningJobs) done(id int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.runs, id)
+	if len(r.runs) == 0 {
+		close(r.idle)
+	}
+}
+
+func (r *runningJobs) count() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return int64(len(r.runs))
+}
+
+// list returns the runs, the earliest started first
+func (r *runningJobs) list() []runningJob {
+	r.mu.Lock()
+	runs := make([]runningJob, 0, len(r.runs))
+	for _, run := range r.runs {
+		runs = append(runs, run)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(runs, func(i, j int) bool { return runs[i].StartedAt.Before(runs[j].StartedAt) })
+	return runs
+}
+
+// wait returns nil once no jobs are running, or the error of ctx if done before
+func (r *runningJobs) wait(ctx context.Context) error {
+	r.mu.Lock()
+	if len(r.runs) == 0 {
+		r.mu.Unlock()
+		return nil
+	}
+	idle := r.idle
+	r.mu.Unlock()
+
+	select {
+	case <-idle:
+		// the jobs triggered meanwhile are waited for too
+		return r.wait(ctx)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
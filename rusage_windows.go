@@ -0,0 +1,10 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// maxRSS is not available on windows.
+func maxRSS(state *os.ProcessState) int64 {
+	return 0
+}
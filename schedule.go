@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"strings"
+	"time"
+)
+
+var cronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// scheduleFields splits the schedule into the fields, without the location prefix like TZ=Asia/Shanghai
+func scheduleFields(expr string) (*time.Location, []string, error) {
+	fields := strings.Fields(expr)
+	if len(fields) <= 0 || !(strings.HasPrefix(fields[0], "TZ=") || strings.HasPrefix(fields[0], "CRON_TZ=")) {
+		return time.Local, fields, nil
+	}
+	_, tz, _ := strings.Cut(fields[0], "=")
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, nil, fmt.Errorf("provided bad location %s: %w", tz, err)
+	}
+	return loc, fields[1:], nil
+}
+
+// parseSchedule parses the schedule of the job, wrapped with the options of the job
+func (job *job) parseSchedule() (cron.Schedule, error) {
+	schedule, err := job.parseExpr(job.resolveAlias(job.Schedule))
+	if err != nil {
+		return nil, err
+	}
+
+	if spec, ok := schedule.(*cron.SpecSchedule); ok && job.DSTPolicy != "" {
+		schedule = &dstSchedule{spec: spec, policy: job.DSTPolicy, job: job}
+	}
+	if len(job.Except) > 0 {
+		if schedule, err = job.exceptSchedule(schedule); err != nil {
+			return nil, err
+		}
+	}
+	if job.BusinessDays {
+		if schedule, err = job.businessSchedule(schedule); err != nil {
+			return nil, err
+		}
+	}
+	if job.InitialDelay > 0 || job.StartAt != "" {
+		if schedule, err = job.delayedSchedule(schedule); err != nil {
+			return nil, err
+		}
+	}
+	return schedule, nil
+}
+
+// resolveAlias returns the schedule of the alias like @nightly, or the expression itself
+func (job *job) resolveAlias(expr string) string {
+	if alias, ok := job.task.loadingSettings().ScheduleAliases[strings.TrimPrefix(expr, "@")]; ok && strings.HasPrefix(expr, "@") {
+		return alias
+	}
+	return expr
+}
+
+// parseExpr parses the forms of the schedules, the cron expression at last
+func (job *job) parseExpr(expr string) (cron.Schedule, error) {
+	if rrule, err := parseRRule(expr); err != nil || rrule != nil {
+		return rrule, err
+	}
+	if aligned, err := parseAligned(expr); err != nil || aligned != nil {
+		return aligned, err
+	}
+	if window, err := parseWindow(expr, job.Name); err != nil || window != nil {
+		return window, err
+	}
+	if solar, err := parseSolar(expr, job.task.loadingSettings().Solar); err != nil || solar != nil {
+		return solar, err
+	}
+	if quartz, err := parseQuartz(expr); err != nil || quartz != nil {
+		return quartz, err
+	}
+	return cronParser.Parse(expr)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const sentryFlushTimeout = 2 * time.Second
+
+// sentryReporter sends the events to the store endpoint of sentry, without the SDK.
+type sentryReporter struct {
+	endpoint    string
+	auth        string
+	environment string
+	serverName  string
+
+	client *http.Client
+	wg     sync.WaitGroup
+}
+
+type sentryException struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Level       string            `json:"level"`
+	Logger      string            `json:"logger"`
+	Platform    string            `json:"platform"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Message     string            `json:"message"`
+	Exception   []sentryException `json:"exception,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Extra       map[string]any    `json:"extra,omitempty"`
+}
+
+// newSentryReporter parses the dsn, like https://<key>@<host>/<project_id>; returns nil if dsn is empty.
+func newSentryReporter(dsn, environment string) (*sentryReporter, error) {
+	if dsn == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sentry dsn: %w", err)
+	}
+	projectID := strings.Trim(u.Path, "/")
+	if u.User == nil || u.User.Username() == "" || projectID == "" {
+		return nil, fmt.Errorf("invalid sentry dsn: %s", dsn)
+	}
+
+	auth := "Sentry sentry_version=7, sentry_client=cron-cli/1.1, sentry_key=" + u.User.Username()
+	if secret, ok := u.User.Password(); ok {
+		auth += ", sentry_secret=" + secret
+	}
+
+	hostname, _ := os.Hostname()
+	return &sentryReporter{
+		endpoint:    fmt.Sprintf("%s://%s/api/%s/store/", u.Scheme, u.Host, projectID),
+		auth:        auth,
+		environment: environment,
+		serverName:  hostname,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// report sends the event in background
+func (s *sentryReporter) report(level string, err error, msg string, tags map[string]string, extra map[string]any) {
+	if s == nil {
+		return
+	}
+
+	e := sentryEvent{
+		EventID:     randomHex(16),
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		Level:       level,
+		Logger:      "cron",
+		Platform:    "go",
+		ServerName:  s.serverName,
+		Environment: s.environment,
+		Message:     msg,
+		Tags:        tags,
+		Extra:       extra,
+	}
+	if err != nil {
+		e.Exception = []sentryException{{Type: fmt.Sprintf("%T", err), Value: err.Error()}}
+	}
+
+	body, marshalErr := json.Marshal(e)
+	if marshalErr != nil {
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		req, reqErr := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(body))
+		if reqErr != nil {
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Sentry-Auth", s.auth)
+		if resp, doErr := s.client.Do(req); doErr == nil {
+			_ = resp.Body.Close()
+		}
+	}()
+}
+
+// flush waits for the sending events, at most sentryFlushTimeout
+func (s *sentryReporter) flush() {
+	if s == nil {
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(sentryFlushTimeout):
+	}
+}
+
+func (t *Task) SetSentry(dsn, environment string) error {
+	reporter, err := newSentryReporter(dsn, environment)
+	if err != nil {
+		return err
+	}
+	t.sentry = reporter
+	return nil
+}
+
+// reportError reports the scheduler errors
+func (t *Task) reportError(err error, msg string) {
+	t.sentry.report("error", err, msg, map[string]string{"component": "scheduler"}, nil)
+}
+
+// reportError reports the job failures and panics with the job metadata
+func (job *job) reportError(level string, err error, msg string, extra map[string]any) {
+	if extra == nil {
+		extra = map[string]any{}
+	}
+	extra["schedule"] = job.Schedule
+	extra["command"] = truncateText(job.Command, 200)
+	extra["config"] = job.configFile
+
+	job.task.sentry.report(level, err, msg, map[string]string{
+		"component": "job",
+		"job":       job.Name,
+	}, extra)
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// httpOptions are the options of the http server, also used by the commands to request a running cron
+type httpOptions struct {
+	Addr  string // like :9100 (on 127.0.0.1), 0.0.0.0:9100 or unix:/run/cron.sock
+	Token string // the bearer token of the API, required to listen on the addresses other than loopback
+}
+
+// listenAddr returns the network and the address to listen on, ":port" is on 127.0.0.1 only
+func (o httpOptions) listenAddr() (network, address string) {
+	if strings.HasPrefix(o.Addr, "unix:") {
+		return "unix", strings.TrimPrefix(o.Addr, "unix:")
+	} else if strings.HasPrefix(o.Addr, ":") {
+		return "tcp", "127.0.0.1" + o.Addr
+	}
+	return "tcp", o.Addr
+}
+
+func isLoopback(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return host == "localhost" || (ip != nil && ip.IsLoopback())
+}
+
+func (t *Task) StartServer(options httpOptions) error {
+	if options.Addr == "" {
+		return nil
+	}
+
+	network, address := options.listenAddr()
+	if network == "tcp" && options.Token == "" && !isLoopback(address) {
+		return fmt.Errorf("--http-token required to listen on %s", address)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", t.handleMetrics)
+	mux.HandleFunc("/events", authorize(options.Token, t.handleEvents))
+	mux.HandleFunc("/jobs", authorize(options.Token, t.handleJobs))
+	mux.HandleFunc("/jobs/export", authorize(options.Token, t.handleExport))
+	mux.HandleFunc("/jobs/history", authorize(options.Token, t.handleHistory))
+	mux.HandleFunc("/jobs/run", authorize(options.Token, t.handleTrigger))
+	mux.HandleFunc("/jobs/enable", authorize(options.Token, t.handleEnable(true)))
+	mux.HandleFunc("/jobs/disable", authorize(options.Token, t.handleEnable(false)))
+	mux.HandleFunc("/reload", authorize(options.Token, t.handleReload))
+	mux.HandleFunc("/drain", authorize(options.Token, t.handleDrain))
+	mux.HandleFunc("/pause", authorize(options.Token, t.handlePause))
+	mux.HandleFunc("/resume", authorize(options.Token, t.handleResume))
+
+	if network == "unix" {
+		// the socket left by a crashed instance
+		if stat, err := os.Stat(address); err == nil && stat.Mode()&os.ModeSocket != 0 {
+			_ = os.Remove(address)
+		}
+	}
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return fmt.Errorf("http server listen error: %w", err)
+	}
+	if network == "unix" {
+		// only the owner can connect, the socket is not authorized by the token
+		if err = os.Chmod(address, 0o600); err != nil {
+			_ = listener.Close()
+			return fmt.Errorf("http server listen error: %w", err)
+		}
+	}
+
+	t.server = &http.Server{Handler: mux}
+	go func() {
+		t.logger.Info("http server listening", "addr", options.Addr)
+		if err := t.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			t.logger.Error(err, "http server error", "addr", options.Addr)
+			t.reportError(err, "http server error")
+		}
+	}()
+	return nil
+}
+
+// authorize rejects the requests without the bearer token, all requests are allowed if the token is empty
+func authorize(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="cron"`)
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("unauthorized"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (t *Task) stopServer() {
+	if t.server == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_ = t.server.Shutdown(ctx)
+}
+
+func (t *Task) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	t.metrics.write(w)
+}
+
+// handleEvents streams the events in the Server-Sent Events format
+func (t *Task) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	flusher.Flush()
+
+	ch, unsubscribe := t.events.subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-ch:
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			if _, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// requestActor is who sent the request, the CLI sets X-Cron-Actor to the local user.
+// The header is claimed by the client, so the remote address is recorded with it, like alice@127.0.0.1:52100.
+func requestActor(r *http.Request) (actor, source string) {
+	remote := r.RemoteAddr
+	if remote == "" || remote == "@" {
+		remote = "unix"
+	}
+	if actor = r.Header.Get("X-Cron-Actor"); actor != "" {
+		return actor + "@" + remote, auditSourceCLI
+	}
+	return remote, auditSourceAPI
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func (t *Task) handleTrigger(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method must be POST"))
+		return
+	}
+
+	actor, source := requestActor(r)
+	if name := r.URL.Query().Get("name"); name != "" || r.URL.Query().Get("tags") == "" {
+		if err := t.TriggerJob(name, actor, source); err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "triggered"})
+		return
+	}
+
+	// all jobs with the tags
+	jobs := t.jobsByTags(requestTags(r))
+	if len(jobs) <= 0 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no jobs with the tags %s", r.URL.Query().Get("tags")))
+		return
+	}
+	var triggered []string
+	for _, j := range jobs {
+		if err := t.TriggerJob(j.Name, actor, source); err != nil {
+			t.logger.Error(err, "trigger job fail", "name", j.Name)
+			continue
+		}
+		triggered = append(triggered, j.Name)
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": fmt.Sprintf("triggered %d/%d: %s", len(triggered), len(jobs), strings.Join(triggered, ", "))})
+}
+
+// requestTags returns the tags of the query like ?tags=db,nightly
+func requestTags(r *http.Request) []string {
+	var tags []string
+	for _, tag := range strings.Split(r.URL.Query().Get("tags"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// jobInfo is a job listed by /jobs
+type jobInfo struct {
+	Name     string     `json:"name"`
+	Schedule string     `json:"schedule"`
+	Tags     []string   `json:"tags"`
+	Next     time.Time  `json:"next"`
+	Disabled bool       `json:"disabled"`
+	Last     *runRecord `json:"last,omitempty"` // the latest run
+}
+
+func (t *Task) handleJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method must be GET"))
+		return
+	}
+
+	// the entries are copied by the loop of cron once
+	next := map[cron.EntryID]time.Time{}
+	for _, entry := range t.Cron.Entries() {
+		next[entry.ID] = entry.Next
+	}
+	jobs := []jobInfo{}
+	for _, j := range t.jobsByTags(requestTags(r)) {
+		info := jobInfo{Name: j.Name, Schedule: j.Schedule, Tags: j.Tags, Next: next[j.id], Disabled: j.state.data().Disabled}
+		if record, ok := j.history.last(); ok {
+			info.Last = &record
+		}
+		jobs = append(jobs, info)
+	}
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+// handleHistory returns the records of the latest runs of the job, the oldest first
+func (t *Task) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method must be GET"))
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	j := t.findJob(name)
+	if j == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job \"%s\" not found", name))
+		return
+	}
+	writeJSON(w, http.StatusOK, j.history.list())
+}
+
+func (t *Task) handleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method must be GET"))
+		return
+	}
+
+	content, err := exportJobs(t.jobsByTags(requestTags(r)))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	_, _ = w.Write(content)
+}
+
+func (t *Task) handleEnable(enabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method must be POST"))
+			return
+		}
+
+		actor, source := requestActor(r)
+		name := r.URL.Query().Get("name")
+		if err := t.SetJobEnabled(name, enabled, actor, source); err != nil {
+			status := http.StatusConflict
+			if t.findJob(name) == nil {
+				status = http.StatusNotFound
+			}
+			writeError(w, status, err)
+			return
+		}
+		if enabled {
+			writeJSON(w, http.StatusOK, map[string]string{"status": "enabled"})
+		} else {
+			writeJSON(w, http.StatusOK, map[string]string{"status": "disabled"})
+		}
+	}
+}
+
+func (t *Task) handleReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method must be POST"))
+		return
+	}
+
+	actor, source := requestActor(r)
+	t.audit.record("reload", actor, source, "", "")
+	if err := t.Reload(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "reloaded"})
+}
+
+func (t *Task) handleDrain(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method must be POST"))
+		return
+	}
+
+	actor, source := requestActor(r)
+	if err := t.Drain(actor, source); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "draining"})
+}
+
+func (t *Task) handlePause(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method must be POST"))
+		return
+	}
+
+	actor, source := requestActor(r)
+	if err := t.Pause(actor, source); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "paused"})
+}
+
+func (t *Task) handleResume(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method must be POST"))
+		return
+	}
+
+	actor, source := requestActor(r)
+	if err := t.Resume(actor, source); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "resumed"})
+}
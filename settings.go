@@ -0,0 +1,132 @@
+package main
+
+// settings are the global options in the yaml configs, the latter config overrides the non-empty fields.
+type settings struct {
+	SMTP   smtpSettings `json:"smtp" yaml:"smtp"`
+	MailTo string       `json:"mailto" yaml:"mailto"`   // the default mailto of jobs
+	MailOn string       `json:"mail_on" yaml:"mail_on"` // the default mail_on of jobs
+
+	Webhooks  []webhookConfig   `json:"webhooks" yaml:"webhooks"` // notify the events of all jobs
+	Slack     slackSettings     `json:"slack" yaml:"slack"`
+	Telegram  telegramSettings  `json:"telegram" yaml:"telegram"`
+	PagerDuty pagerdutySettings `json:"pagerduty" yaml:"pagerduty"`
+	Opsgenie  opsgenieSettings  `json:"opsgenie" yaml:"opsgenie"`
+
+	NotifyOutputLines int    `json:"notify_output_lines" yaml:"notify_output_lines"` // the default notify_output_lines of jobs
+	MessageTemplate   string `json:"message_template" yaml:"message_template"`       // the default message_template of jobs
+
+	Redis   redisSettings  `json:"redis" yaml:"redis"`       // the backend of lock: redis
+	Etcd    etcdSettings   `json:"etcd" yaml:"etcd"`         // the backend of lock: etcd
+	Consul  consulSettings `json:"consul" yaml:"consul"`     // the backend of lock: consul
+	LockDir string         `json:"lock_dir" yaml:"lock_dir"` // the directory on the shared storage for lock: file
+
+	Docker dockerSettings `json:"docker" yaml:"docker"` // the connection of the docker cli for the container features
+
+	ScheduleAliases map[string]string `json:"schedule_aliases" yaml:"schedule_aliases"` // the schedules referenced by @<name> in the jobs of this and the latter configs, like nightly: "0 30 2 * * *"
+	Workweek        string            `json:"workweek" yaml:"workweek"`                 // the default workweek of the jobs with business_days_only, like SUN-THU
+	Holidays        []string          `json:"holidays" yaml:"holidays"`                 // the dates skipped by the jobs with business_days_only, like [2024-12-25, 2025-01-01]
+	Solar           solarSettings     `json:"solar" yaml:"solar"`                       // the position of the solar schedules, like @sunset+30m
+
+	LeaderElection leaderSettings `json:"leader_election" yaml:"leader_election"` // only the elected instance schedules jobs
+
+	StoppingTimeout duration `json:"stopping_timeout" yaml:"stopping_timeout"` // the default stopping_timeout of jobs, overrides --stopping-timeout
+}
+
+func (s *settings) merge(other settings) {
+	if other.SMTP.Host != "" {
+		s.SMTP = other.SMTP
+	}
+	if other.MailTo != "" {
+		s.MailTo = other.MailTo
+	}
+	if other.MailOn != "" {
+		s.MailOn = other.MailOn
+	}
+	if len(other.Webhooks) > 0 {
+		s.Webhooks = other.Webhooks
+	}
+	if other.NotifyOutputLines != 0 {
+		s.NotifyOutputLines = other.NotifyOutputLines
+	}
+	if other.MessageTemplate != "" {
+		s.MessageTemplate = other.MessageTemplate
+	}
+	if other.Redis.Addr != "" {
+		s.Redis = other.Redis
+	}
+	if len(other.Etcd.Endpoints) > 0 {
+		s.Etcd = other.Etcd
+	}
+	if other.Consul.Addr != "" {
+		s.Consul = other.Consul
+	}
+	if other.LockDir != "" {
+		s.LockDir = other.LockDir
+	}
+	if other.Docker.Engine != "" || other.Docker.Host != "" || other.Docker.CertPath != "" || other.Docker.TLSVerify {
+		s.Docker = other.Docker
+	}
+	if len(other.ScheduleAliases) > 0 { // a new map, the previous one may be read by the copies
+		aliases := make(map[string]string, len(s.ScheduleAliases)+len(other.ScheduleAliases))
+		for name, schedule := range s.ScheduleAliases {
+			aliases[name] = schedule
+		}
+		for name, schedule := range other.ScheduleAliases {
+			aliases[name] = schedule
+		}
+		s.ScheduleAliases = aliases
+	}
+	if other.Workweek != "" {
+		s.Workweek = other.Workweek
+	}
+	if len(other.Holidays) > 0 {
+		s.Holidays = other.Holidays
+	}
+	if other.Solar.Latitude != 0 || other.Solar.Longitude != 0 {
+		s.Solar = other.Solar
+	}
+	if other.StoppingTimeout > 0 {
+		s.StoppingTimeout = other.StoppingTimeout
+	}
+	if other.LeaderElection.Lock != "" {
+		s.LeaderElection = other.LeaderElection
+	}
+	if other.Slack.enabled() {
+		s.Slack = other.Slack
+	}
+	s.Telegram = s.Telegram.override(other.Telegram)
+	if other.PagerDuty.RoutingKey != "" {
+		s.PagerDuty = other.PagerDuty
+	}
+	if other.Opsgenie.APIKey != "" {
+		s.Opsgenie = other.Opsgenie
+	}
+}
+
+// getSettings returns the settings of the loaded configs, which are not changed by a reload in progress
+func (t *Task) getSettings() settings {
+	t.settingsMu.RLock()
+	defer t.settingsMu.RUnlock()
+	return t.settings
+}
+
+// loadingSettings returns the settings of the configs being reloaded for the jobs being parsed, or the loaded ones
+func (t *Task) loadingSettings() settings {
+	t.settingsMu.RLock()
+	defer t.settingsMu.RUnlock()
+	if t.loading != nil {
+		return *t.loading
+	}
+	return t.settings
+}
+
+// mergeSettings merges the settings of a config into the settings being reloaded, or the loaded ones when starting
+func (t *Task) mergeSettings(other settings) {
+	t.settingsMu.Lock()
+	defer t.settingsMu.Unlock()
+	if t.loading != nil {
+		t.loading.merge(other)
+	} else {
+		t.settings.merge(other)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import "runtime"
+
+// the interpreters of the shell jobs, cmd by default on windows, sh by default on the others
+var shellInterpreters = []string{"sh", "cmd", "powershell", "pwsh"}
+
+// shellName returns the interpreter of the job, sh in docker mode that runs the commands on the host via nsenter
+func (job *job) shellName() string {
+	if job.Shell != "" {
+		return job.Shell
+	} else if runtime.GOOS == "windows" && !job.task.InDocker() {
+		return "cmd"
+	}
+	return "sh"
+}
+
+// shell returns the interpreter of the command
+func (job *job) shell() []string {
+	if job.task.InDocker() && len(job.HostNamespaces) > 0 {
+		return append(job.nsenter(), "/bin/sh")
+	} else if job.task.InDocker() {
+		return []string{"nsenter", "-t", "1", "-m", "-u", "-n", "-i", "/usr/bin/sh"}
+	}
+
+	switch job.shellName() {
+	case "cmd":
+		return []string{"c:\\windows\\system32\\cmd.exe", "/D"} // without the AutoRun of the registry
+	case "powershell", "pwsh":
+		return []string{job.shellName(), "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass"}
+	}
+	if runtime.GOOS == "windows" {
+		return []string{"sh"} // like git bash, found in PATH
+	}
+	return []string{"/usr/bin/sh"}
+}
+
+// shellArgs returns the arguments of the interpreter to run the shell file, or the command from stdin if file is "-"
+func (job *job) shellArgs(file string) []string {
+	switch job.shellName() {
+	case "cmd":
+		return []string{"/C", file}
+	case "powershell", "pwsh":
+		if file == "-" {
+			return []string{"-Command", "-"}
+		}
+		return []string{"-File", file}
+	}
+	if file == "-" {
+		return []string{"-s"}
+	}
+	return []string{file}
+}
+
+// shellInline returns the arguments of the interpreter to run the command line, like the hooks
+func (job *job) shellInline(command string) []string {
+	switch job.shellName() {
+	case "cmd":
+		return []string{"/C", command}
+	case "powershell", "pwsh":
+		return []string{"-Command", command}
+	}
+	return []string{"-c", command}
+}
+
+// shellExt returns the extension of the shell file, cmd and powershell tell the type of script by it
+func (job *job) shellExt() string {
+	switch job.shellName() {
+	case "cmd":
+		return ".cmd"
+	case "powershell", "pwsh":
+		return ".ps1"
+	}
+	return ".sh"
+}
+
+// shellScript returns the content of the shell file, the commands are not echoed by cmd
+func (job *job) shellScript(script string) []byte {
+	if job.shellName() == "cmd" {
+		return []byte("@echo off\r\n" + script)
+	}
+	return []byte(script)
+}
@@ -0,0 +1,14 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// there is no SIGUSR1 on windows, drain via the http server
+var drainSignals []os.Signal
+
+// ctrl+c and ctrl+break, and SIGTERM of the console closed, the user logged off or the system shut down
+var stopSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
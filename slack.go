@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const slackPostMessageURL = "https://slack.com/api/chat.postMessage"
+
+type slackSettings struct {
+	WebhookURL string   `json:"webhook_url" yaml:"webhook_url"` // the incoming webhook, or
+	Token      string   `json:"token" yaml:"token"`             // the bot token, which posts to the channel
+	Channel    string   `json:"channel" yaml:"channel"`         // the default channel, overridden by slack_channel of jobs
+	Events     []string `json:"events" yaml:"events"`           // [start, success, failure, recovery, circuit_open, budget_exceeded], [failure, recovery, circuit_open, budget_exceeded] by default
+}
+
+func (s slackSettings) enabled() bool {
+	return s.WebhookURL != "" || s.Token != ""
+}
+
+type slackNotifier struct {
+	settings slackSettings
+	channel  string
+}
+
+var slackClient = &http.Client{Timeout: 10 * time.Second}
+
+func (s slackNotifier) accepts(event string) bool {
+	return acceptsEvent(s.settings.Events, event, notifyFailure, notifyRecovery, notifyCircuit, notifyBudget)
+}
+
+func (s slackNotifier) retries() int {
+	return defaultWebhookRetries
+}
+
+func (s slackNotifier) String() string {
+	return "slack " + s.channel
+}
+
+func slackText(n *notification) string {
+	if n.Message != "" {
+		return n.Message
+	}
+
+	var text string
+	switch n.Event {
+	case notifyFailure:
+		text = fmt.Sprintf(":x: *%s* failed on %s", n.Job, n.Hostname)
+	case notifyRecovery:
+		text = fmt.Sprintf(":white_check_mark: *%s* recovered on %s", n.Job, n.Hostname)
+	case notifyCircuit:
+		text = fmt.Sprintf(":no_entry: *%s* disabled by the circuit breaker on %s", n.Job, n.Hostname)
+	case notifyBudget:
+		text = fmt.Sprintf(":hourglass: *%s* exceeded the daily budget on %s, skipped until tomorrow", n.Job, n.Hostname)
+	case notifySuccess:
+		text = fmt.Sprintf(":white_check_mark: *%s* succeeded on %s", n.Job, n.Hostname)
+	default:
+		text = fmt.Sprintf(":arrow_forward: *%s* started on %s", n.Job, n.Hostname)
+	}
+	if n.Event != notifyStart {
+		text += fmt.Sprintf("\nexit code: `%d`, duration: `%s`", n.ExitCode, n.Duration)
+	}
+	if n.Error != "" {
+		text += fmt.Sprintf("\nerror: `%s`", n.Error)
+	}
+	if n.Output != "" {
+		text += "\n```" + n.Output + "```"
+	}
+	return text
+}
+
+func (s slackNotifier) send(n *notification) error {
+	payload := map[string]string{"text": slackText(n)}
+	if s.channel != "" {
+		payload["channel"] = s.channel
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	if s.settings.Token == "" {
+		req, err := http.NewRequest(http.MethodPost, s.settings.WebhookURL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return doRequest(slackClient, req)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, slackPostMessageURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+s.settings.Token)
+
+	resp, err := slackClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// the web api responds 200 with ok=false on errors
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	if !result.OK {
+		return fmt.Errorf("slack error: %s", result.Error)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// the solar events of the schedules like @sunset+30m or @sunrise-1h, the altitudes of the sun in degrees
+var solarEvents = map[string]float64{
+	"sunrise": -0.833,
+	"sunset":  -0.833,
+	"dawn":    -6, // civil
+	"dusk":    -6,
+}
+
+// solarSettings is the position of the solar schedules
+type solarSettings struct {
+	Latitude  float64 `json:"latitude" yaml:"latitude"`   // north is positive, like 31.23
+	Longitude float64 `json:"longitude" yaml:"longitude"` // east is positive, like 121.47
+}
+
+// solarSchedule fires at the solar event of every day, recomputed for each day
+type solarSchedule struct {
+	event    string
+	offset   time.Duration
+	position solarSettings
+}
+
+// parseSolar parses the schedule like @sunset+30m, returns nil if not a solar event
+func parseSolar(expr string, position solarSettings) (*solarSchedule, error) {
+	if !strings.HasPrefix(expr, "@") {
+		return nil, nil
+	}
+	event, offset := expr[1:], ""
+	if i := strings.IndexAny(event, "+-"); i >= 0 {
+		event, offset = event[:i], event[i:]
+	}
+	if _, ok := solarEvents[event]; !ok {
+		return nil, nil
+	}
+
+	s := &solarSchedule{event: event, position: position}
+	if offset != "" {
+		d, err := time.ParseDuration(offset)
+		if err != nil {
+			return nil, fmt.Errorf("invalid offset of @%s: %w", event, err)
+		}
+		s.offset = d
+	}
+	if position.Latitude == 0 && position.Longitude == 0 {
+		return nil, fmt.Errorf("solar.latitude and solar.longitude of settings required by @%s", event)
+	}
+	if math.Abs(position.Latitude) > 90 || math.Abs(position.Longitude) > 180 {
+		return nil, fmt.Errorf("solar.latitude must be [-90, 90] and solar.longitude must be [-180, 180]")
+	}
+	return s, nil
+}
+
+func (s *solarSchedule) Next(t time.Time) time.Time {
+	// from the day before, the event of a day may be on the previous day in UTC. No events in polar days and nights
+	day := t.UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+	for i := 0; i < 400; i++ {
+		if event, ok := s.at(day.AddDate(0, 0, i)); ok {
+			if next := event.Add(s.offset).Truncate(time.Second); next.After(t) {
+				return next.In(t.Location())
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// at returns the time of the event on the day, by the sunrise equation
+func (s *solarSchedule) at(day time.Time) (time.Time, bool) {
+	const (
+		j2000 = 2451545.0
+		unix  = 2440587.5 // the julian day of 1970-01-01
+	)
+	rad := math.Pi / 180
+
+	// the mean solar noon
+	n := math.Ceil(float64(day.Unix())/86400 + unix - j2000 + 0.0008)
+	noon := n - s.position.Longitude/360
+
+	m := math.Mod(357.5291+0.98560028*noon, 360)
+	c := 1.9148*math.Sin(m*rad) + 0.02*math.Sin(2*m*rad) + 0.0003*math.Sin(3*m*rad)
+	lambda := math.Mod(m+c+180+102.9372, 360)
+	transit := j2000 + noon + 0.0053*math.Sin(m*rad) - 0.0069*math.Sin(2*lambda*rad)
+
+	declination := math.Asin(math.Sin(lambda*rad) * math.Sin(23.4397*rad))
+	latitude := s.position.Latitude * rad
+	cosHourAngle := (math.Sin(solarEvents[s.event]*rad) - math.Sin(latitude)*math.Sin(declination)) / (math.Cos(latitude) * math.Cos(declination))
+	if cosHourAngle < -1 || cosHourAngle > 1 {
+		return time.Time{}, false
+	}
+
+	hourAngle := math.Acos(cosHourAngle) / rad
+	julian := transit + hourAngle/360
+	if s.event == "sunrise" || s.event == "dawn" {
+		julian = transit - hourAngle/360
+	}
+	return time.Unix(0, int64((julian-unix)*86400*float64(time.Second))), true
+}
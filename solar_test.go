@@ -0,0 +1,100 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSolarNext(t *testing.T) {
+	london := solarSettings{Latitude: 51.5074, Longitude: -0.1278}
+	newYork := solarSettings{Latitude: 40.7128, Longitude: -74.006}
+	shanghai := solarSettings{Latitude: 31.23, Longitude: 121.47}
+
+	// within minutes of the almanacs, the refraction differs
+	tests := []struct {
+		expr     string
+		position solarSettings
+		from     string
+		want     string
+	}{
+		{"@sunrise", london, "2024-06-21T00:00:00Z", "2024-06-21T03:43:00Z"},
+		{"@sunset", london, "2024-06-21T00:00:00Z", "2024-06-21T20:21:00Z"},
+		{"@sunset", london, "2024-06-21T20:30:00Z", "2024-06-22T20:21:00Z"},    // after the sunset of the day
+		{"@sunset", newYork, "2024-03-10T12:00:00Z", "2024-03-10T22:58:00Z"},   // the day of DST
+		{"@sunrise", shanghai, "2024-12-21T12:00:00Z", "2024-12-21T22:51:00Z"}, // the previous day in UTC
+		{"@sunset", shanghai, "2024-12-21T00:00:00Z", "2024-12-21T08:54:00Z"},
+		{"@dusk", london, "2024-12-21T00:00:00Z", "2024-12-21T16:34:00Z"},
+		{"@sunset-1h", london, "2024-06-21T00:00:00Z", "2024-06-21T19:21:00Z"},
+		{"@sunrise+30m", london, "2024-06-21T00:00:00Z", "2024-06-21T04:13:00Z"},
+	}
+	for _, test := range tests {
+		t.Run(test.expr+" from "+test.from, func(t *testing.T) {
+			schedule, err := parseSolar(test.expr, test.position)
+			if err != nil || schedule == nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			from, _ := time.Parse(time.RFC3339, test.from)
+			want, _ := time.Parse(time.RFC3339, test.want)
+			got := schedule.Next(from)
+			if diff := got.Sub(want); diff < -3*time.Minute || diff > 3*time.Minute {
+				t.Errorf("Next() = %s, want %s", got.UTC().Format(time.RFC3339), test.want)
+			}
+		})
+	}
+}
+
+func TestSolarPolar(t *testing.T) {
+	tromso := solarSettings{Latitude: 69.65, Longitude: 18.96}
+	tests := []struct {
+		expr   string
+		from   string
+		after  string // the first event after the polar night or day
+		before string
+	}{
+		{"@sunrise", "2024-12-21T00:00:00Z", "2025-01-10T00:00:00Z", "2025-01-20T00:00:00Z"},
+		{"@sunset", "2024-06-21T00:00:00Z", "2024-07-18T00:00:00Z", "2024-07-28T00:00:00Z"},
+	}
+	for _, test := range tests {
+		t.Run(test.expr+" from "+test.from, func(t *testing.T) {
+			schedule, err := parseSolar(test.expr, tromso)
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			from, _ := time.Parse(time.RFC3339, test.from)
+			after, _ := time.Parse(time.RFC3339, test.after)
+			before, _ := time.Parse(time.RFC3339, test.before)
+			if got := schedule.Next(from); got.Before(after) || got.After(before) {
+				t.Errorf("Next() = %s, want between %s and %s", got.UTC().Format(time.RFC3339), test.after, test.before)
+			}
+		})
+	}
+}
+
+func TestParseSolar(t *testing.T) {
+	position := solarSettings{Latitude: 31.23, Longitude: 121.47}
+	tests := []struct {
+		expr     string
+		position solarSettings
+		solar    bool
+		wantErr  bool
+	}{
+		{"@daily", position, false, false},
+		{"0 0 * * *", position, false, false},
+		{"@sunset", position, true, false},
+		{"@sunrise-90m", position, true, false},
+		{"@sunset+1d", position, true, true},
+		{"@sunset", solarSettings{}, true, true}, // the position required
+		{"@sunset", solarSettings{Latitude: 91, Longitude: 0}, true, true},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			schedule, err := parseSolar(test.expr, test.position)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error = %v, want error %v", err, test.wantErr)
+			}
+			if !test.wantErr && (schedule != nil) != test.solar {
+				t.Errorf("solar = %v, want %v", schedule != nil, test.solar)
+			}
+		})
+	}
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const minIntervalTolerance = 500 * time.Millisecond
+
+const stateSaveDelay = time.Second // the changes meanwhile are saved at once
+
+type jobStateData struct {
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	Alerted             bool      `json:"alerted"` // failure alerts were sent and not recovered yet
+	LastRunAt           time.Time `json:"last_run_at"`
+	LastSuccessAt       time.Time `json:"last_success_at"`
+	LastFailureAt       time.Time `json:"last_failure_at"`
+	LastAlertAt         time.Time `json:"last_alert_at"`
+	LastAlertPrint      string    `json:"last_alert_print"`   // the fingerprint of the last alert
+	CircuitOpenUntil    time.Time `json:"circuit_open_until"` // the runs are skipped until then by the circuit breaker
+	Disabled            bool      `json:"disabled"`           // disabled at runtime, the firings are skipped
+	BudgetDay           string    `json:"budget_day"`         // the day of budget_used, like 2006-01-02
+	BudgetUsed          duration  `json:"budget_used"`        // the total runtime of the budget day
+
+	Processes []processRecord `json:"processes,omitempty"` // the running commands, left behind if the instance crashed
+}
+
+type processRecord struct {
+	PID       int       `json:"pid"`
+	RunID     string    `json:"run_id"`
+	ShellFile string    `json:"shell_file"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// jobState is the run state of a job, which lives across runs, reloads, and restarts if the state file is set
+type jobState struct {
+	mu sync.Mutex
+	jobStateData
+}
+
+// update applies the record, returns the consecutive failures (before the record if succeeded) and whether alerts were sent before
+func (s *jobState) update(record runRecord) (failures int, alerted bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alerted = s.Alerted
+	s.LastRunAt = record.StartedAt
+	if record.success() {
+		failures = s.ConsecutiveFailures
+		s.ConsecutiveFailures = 0
+		s.Alerted = false
+		s.LastSuccessAt = record.StartedAt
+		return failures, alerted
+	}
+
+	s.ConsecutiveFailures++
+	s.LastFailureAt = record.StartedAt
+	return s.ConsecutiveFailures, alerted
+}
+
+// allowAlert checks the throttle and the dedupe, the alert is recorded if allowed, otherwise returns the reason
+func (s *jobState) allowAlert(now time.Time, fingerprint string, throttle time.Duration, dedupe bool) (bool, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Alerted && dedupe && s.LastAlertPrint == fingerprint {
+		return false, "duplicated"
+	}
+	if throttle > 0 && !s.LastAlertAt.IsZero() && now.Sub(s.LastAlertAt) < throttle {
+		return false, "throttled"
+	}
+
+	s.Alerted = true
+	s.LastAlertAt = now
+	s.LastAlertPrint = fingerprint
+	return true, ""
+}
+
+// claimRun marks the run started, returns false with the last start if it is within minInterval
+func (s *jobState) claimRun(now time.Time, minInterval time.Duration) (bool, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the firings are not exactly on time, tolerate the latency of starting
+	last := s.LastRunAt
+	if minInterval > 0 && !last.IsZero() && now.Sub(last) < minInterval-minIntervalTolerance {
+		return false, last
+	}
+	s.LastRunAt = now
+	return true, last
+}
+
+// setDisabled returns false if the state is unchanged
+func (s *jobState) setDisabled(disabled bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Disabled == disabled {
+		return false
+	}
+	s.Disabled = disabled
+	return true
+}
+
+func (s *jobState) addProcess(p processRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Processes = append(s.Processes, p)
+}
+
+func (s *jobState) removeProcess(pid int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var remained []processRecord
+	for _, p := range s.Processes {
+		if p.PID != pid {
+			remained = append(remained, p)
+		}
+	}
+	s.Processes = remained
+}
+
+// takeProcesses returns and clears the processes
+func (s *jobState) takeProcesses() []processRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	processes := s.Processes
+	s.Processes = nil
+	return processes
+}
+
+// budgetDay returns the calendar day of daily_budget
+func budgetDay(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// budgetUsed returns the total runtime of the day
+func (s *jobState) budgetUsed(day string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.BudgetDay != day {
+		return 0
+	}
+	return time.Duration(s.BudgetUsed)
+}
+
+// addRuntime adds the runtime to the day, returns the total and whether the budget is exceeded by the runtime
+func (s *jobState) addRuntime(day string, runtime, budget time.Duration) (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.BudgetDay != day {
+		s.BudgetDay, s.BudgetUsed = day, 0
+	}
+	before := time.Duration(s.BudgetUsed)
+	s.BudgetUsed += duration(runtime)
+	used := time.Duration(s.BudgetUsed)
+	return used, budget > 0 && before < budget && used >= budget
+}
+
+// openCircuit skips the runs until the time
+func (s *jobState) openCircuit(until time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.CircuitOpenUntil = until
+}
+
+func (s *jobState) data() jobStateData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jobStateData
+}
+
+// stateStore keeps the states of all jobs by name, which are saved to the file if the path is set
+type stateStore struct {
+	mu     sync.Mutex
+	path   string
+	states map[string]*jobState // by stateKey
+
+	saveMu sync.Mutex
+	saving *time.Timer // the pending save
+}
+
+// stateKey identifies the state of a job by the config file and the name,
+// the discovered jobs are named after the containers, so not by the ids which change when redeployed
+func stateKey(configFile, name string) string {
+	if strings.HasPrefix(configFile, discoveryConfigFile) {
+		configFile = discoveryConfigFile
+	}
+	return configFile + "#" + name
+}
+
+func newStateStore(path string) (*stateStore, error) {
+	store := &stateStore{path: path, states: map[string]*jobState{}}
+	if path == "" {
+		return store, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read state file error: %w", err)
+	}
+
+	var saved map[string]jobStateData
+	if err = json.Unmarshal(content, &saved); err != nil {
+		return nil, fmt.Errorf("unmarshal state file \"%s\" error: %w", path, err)
+	}
+	for name, data := range saved {
+		store.states[name] = &jobState{jobStateData: data}
+	}
+	return store, nil
+}
+
+// get returns the state of the job, created if not exists.
+// The state of the name only is taken if any, the state files before were by names.
+func (s *stateStore) get(configFile, name string) *jobState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := stateKey(configFile, name)
+	state, ok := s.states[key]
+	if !ok {
+		if state, ok = s.states[name]; ok {
+			delete(s.states, name)
+		} else {
+			state = &jobState{}
+		}
+		s.states[key] = state
+	}
+	return state
+}
+
+// all returns the states of all jobs by stateKey, including the jobs removed
+func (s *stateStore) all() map[string]*jobState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	states := make(map[string]*jobState, len(s.states))
+	for name, state := range s.states {
+		states[name] = state
+	}
+	return states
+}
+
+// save writes all states to the file atomically
+func (s *stateStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved := make(map[string]jobStateData, len(s.states))
+	for name, state := range s.states {
+		saved[name] = state.data()
+	}
+	content, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(s.path), os.ModePerm); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err = os.WriteFile(tmp, content, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (t *Task) SetStateFile(path string) error {
+	store, err := newStateStore(path)
+	if err != nil {
+		return err
+	}
+	t.states = store
+	return nil
+}
+
+// saveStates saves the states after stateSaveDelay, so the runs starting and finishing meanwhile write the file once
+func (t *Task) saveStates() {
+	s := t.states
+	if s.path == "" {
+		return
+	}
+
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	if s.saving != nil {
+		return
+	}
+	s.saving = time.AfterFunc(stateSaveDelay, func() {
+		s.saveMu.Lock()
+		s.saving = nil
+		s.saveMu.Unlock()
+		if err := s.save(); err != nil {
+			t.logger.Error(err, "save state file fail", "path", s.path)
+		}
+	})
+}
+
+// flushStates saves the states at once, the pending save is canceled
+func (t *Task) flushStates() {
+	s := t.states
+	s.saveMu.Lock()
+	if s.saving != nil {
+		s.saving.Stop()
+		s.saving = nil
+	}
+	s.saveMu.Unlock()
+	if err := s.save(); err != nil {
+		t.logger.Error(err, "save state file fail", "path", s.path)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	defaultAnomalyFactor = 3
+	// minimum records before flagging anomalies, the median of a few runs means nothing
+	anomalyMinSamples = 5
+)
+
+type durationStats struct {
+	Count  int           `json:"count"`
+	Min    time.Duration `json:"min"`
+	Max    time.Duration `json:"max"`
+	Mean   time.Duration `json:"mean"`
+	Median time.Duration `json:"median"`
+	P95    time.Duration `json:"p95"`
+}
+
+func computeDurationStats(records []runRecord) durationStats {
+	if len(records) <= 0 {
+		return durationStats{}
+	}
+
+	durations := make([]time.Duration, len(records))
+	var sum time.Duration
+	for i, record := range records {
+		durations[i] = record.Duration
+		sum += record.Duration
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	n := len(durations)
+	median := durations[n/2]
+	if n%2 == 0 {
+		median = (durations[n/2-1] + durations[n/2]) / 2
+	}
+
+	return durationStats{
+		Count:  n,
+		Min:    durations[0],
+		Max:    durations[n-1],
+		Mean:   sum / time.Duration(n),
+		Median: median,
+		P95:    durations[(n*95-1)/100],
+	}
+}
+
+// checkDuration compares the record with the stats of previous runs, warns if it is abnormally slow.
+func (job *job) checkDuration(log *logger, record runRecord) {
+	stats := computeDurationStats(job.history.list())
+	metrics := job.task.metrics
+
+	factor := job.AnomalyFactor
+	if factor == 0 {
+		factor = defaultAnomalyFactor
+	}
+	if factor > 0 && stats.Count >= anomalyMinSamples && stats.Median > 0 &&
+		float64(record.Duration) > factor*float64(stats.Median) {
+		metrics.inc("cron_job_slow_runs_total", "job", job.Name)
+		job.task.statsd.count("slow_runs", 1, "job", job.Name)
+		log.Warn("run slower than usual", "name", job.Name, "id", job.id, "duration", record.Duration.String(),
+			"median", stats.Median.String(), "factor", factor)
+	}
+
+	if stats.Count > 0 {
+		metrics.set("cron_job_median_duration_seconds", stats.Median.Seconds(), "job", job.Name)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+type statsdOptions struct {
+	Addr      string   // host:port of udp
+	Prefix    string   // prefix of all metric names
+	Tags      []string // global tags, like env:prod
+	DogStatsD bool     // append the tags in the DogStatsD format, the values of tags are in the names in plain StatsD
+}
+
+// statsdClient emits the metrics over udp, all methods are no-op if the client is nil.
+type statsdClient struct {
+	conn    net.Conn
+	options statsdOptions
+}
+
+func newStatsdClient(options statsdOptions) (*statsdClient, error) {
+	if options.Addr == "" {
+		return nil, nil
+	}
+
+	conn, err := net.Dial("udp", options.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial statsd error: %w", err)
+	}
+	return &statsdClient{conn: conn, options: options}, nil
+}
+
+// send writes a line like <prefix><name>:<value>|<type>|#<tags>, tags are [k1, v1, k2, v2...].
+// Plain StatsD has no tags, the values are in the name instead, like cron.<job>.<result>.runs.
+func (s *statsdClient) send(name, value, typ string, tags ...string) {
+	if s == nil {
+		return
+	}
+
+	var line string
+	if s.options.DogStatsD {
+		line = s.options.Prefix + name + ":" + value + "|" + typ
+		all := append([]string{}, s.options.Tags...)
+		for i := 0; i+1 < len(tags); i += 2 {
+			all = append(all, tags[i]+":"+tags[i+1])
+		}
+		if len(all) > 0 {
+			line += "|#" + strings.Join(all, ",")
+		}
+	} else {
+		var parts []string
+		for i := 1; i < len(tags); i += 2 {
+			parts = append(parts, statsdNamePart(tags[i]))
+		}
+		line = s.options.Prefix + strings.Join(append(parts, name), ".") + ":" + value + "|" + typ
+	}
+
+	// udp, ignore the errors
+	_, _ = s.conn.Write([]byte(line))
+}
+
+// statsdNamePart replaces the characters breaking the names of metrics, like . : | @ and spaces
+func statsdNamePart(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, value)
+}
+
+func (s *statsdClient) count(name string, delta int64, tags ...string) {
+	s.send(name, fmt.Sprintf("%d", delta), "c", tags...)
+}
+
+func (s *statsdClient) timing(name string, d time.Duration, tags ...string) {
+	s.send(name, fmt.Sprintf("%d", d.Milliseconds()), "ms", tags...)
+}
+
+func (s *statsdClient) close() {
+	if s != nil {
+		_ = s.conn.Close()
+	}
+}
+
+func (t *Task) SetStatsd(options statsdOptions) error {
+	client, err := newStatsdClient(options)
+	if err != nil {
+		return err
+	}
+	t.statsd = client
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	sdReady    = "READY=1"
+	sdStopping = "STOPPING=1"
+	sdWatchdog = "WATCHDOG=1"
+)
+
+// sdNotify sends the state to systemd, it is a no-op if not run by systemd with Type=notify.
+func sdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	// abstract socket
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// sdWatchdogInterval returns the interval of WATCHDOG=1 pings, which is the half of WatchdogSec; 0 if disabled.
+func sdWatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond / 2
+}
+
+func (t *Task) notifySystemd(state string) {
+	if err := sdNotify(state); err != nil {
+		t.logger.Error(err, "notify systemd fail", "state", state)
+	}
+}
+
+// startSystemdWatchdog pings the watchdog of systemd until ctx is done.
+// The ping is skipped if the scheduler is wedged, so systemd restarts the service.
+func (t *Task) startSystemdWatchdog(ctx context.Context) {
+	interval := sdWatchdogInterval()
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if t.schedulerAlive(interval / 2) {
+					t.notifySystemd(sdWatchdog)
+				} else {
+					t.logger.Error(fmt.Errorf("no response from the scheduler"), "systemd watchdog skipped")
+				}
+			}
+		}
+	}()
+}
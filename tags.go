@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"strings"
+)
+
+// matchTags returns true if the job has any of the tags, or no tags to filter
+func (job *job) matchTags(tags []string) bool {
+	if len(tags) <= 0 {
+		return true
+	}
+	for _, tag := range job.Tags {
+		if containsString(tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// jobsByTags returns the loaded jobs with any of the tags, all jobs if no tags
+func (t *Task) jobsByTags(tags []string) []*job {
+	t.jobsMu.RLock()
+	defer t.jobsMu.RUnlock()
+
+	var jobs []*job
+	for _, j := range t.Jobs {
+		if j.matchTags(tags) {
+			jobs = append(jobs, j)
+		}
+	}
+	return jobs
+}
+
+// exportJobs returns the yaml config of the jobs, without the empty fields and the settings, the secrets are redacted
+func exportJobs(jobs []*job) ([]byte, error) {
+	schedules := make([]any, 0, len(jobs))
+	for _, j := range jobs {
+		content, err := yaml.Marshal(j)
+		if err != nil {
+			return nil, err
+		}
+		var fields map[string]any
+		if err = yaml.Unmarshal(content, &fields); err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, redactSecrets("", omitEmpty(fields)))
+	}
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(map[string]any{"schedules": schedules}); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), encoder.Close()
+}
+
+// omitEmpty removes the zero values of the fields recursively, returns nil if all removed
+func omitEmpty(v any) any {
+	switch value := v.(type) {
+	case map[string]any:
+		for key, field := range value {
+			if value[key] = omitEmpty(field); value[key] == nil {
+				delete(value, key)
+			}
+		}
+		if len(value) <= 0 {
+			return nil
+		}
+		return value
+	case []any:
+		if len(value) <= 0 {
+			return nil
+		}
+		return value
+	case string:
+		if value == "" || value == "0s" {
+			return nil
+		}
+	}
+	if v == nil || reflect.ValueOf(v).IsZero() {
+		return nil
+	}
+	return v
+}
+
+// redactSecrets redacts the values of env and headers, the tokens and the urls of the fields recursively
+func redactSecrets(key string, v any) any {
+	switch value := v.(type) {
+	case map[string]any:
+		for k, field := range value {
+			if key == "headers" {
+				value[k] = "[redacted]"
+			} else {
+				value[k] = redactSecrets(k, field)
+			}
+		}
+	case []any:
+		for i, item := range value {
+			if s, ok := item.(string); ok && key == "env" {
+				name, _, _ := strings.Cut(s, "=")
+				value[i] = name + "=[redacted]"
+			} else {
+				value[i] = redactSecrets(key, item)
+			}
+		}
+	case string:
+		switch key {
+		case "token":
+			return "[redacted]"
+		case "url", "ping_url":
+			return redactURL(value)
+		}
+	}
+	return v
+}
@@ -5,42 +5,108 @@ import (
 	"errors"
 	"fmt"
 	"github.com/robfig/cron/v3"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"sync"
 	"sync/atomic"
 	"syscall"
+	"text/template"
 	"time"
 )
 
-type Task struct {
-	Jobs            []*job
-	Cron            *cron.Cron
-	runningCount    int64
-	stoppingTimeout int64
+const (
+	backgroundTimeout      = 10 * time.Second
+	defaultStoppingTimeout = 3 * time.Second
+)
 
-	wg *sync.WaitGroup
+type Task struct {
+	Jobs        []*job
+	Cron        *cron.Cron
+	running     runningJobs
+	stopTimeout time.Duration // the default stopping timeout of jobs, overridden by the settings
+	leading     int32         // 1 if elected
+	electing    bool
+	draining    int32 // 1 if draining
+	paused      int32 // 1 if paused
+	orphans     string
+	discovery   bool     // discover the jobs of the labeled docker containers
+	sidecar     string   // schedule the jobs of the docker container only
+	tags        []string // load only the jobs with any of the tags
+	reloading   *reloadState
+	stopping    int32 // 1 if stopping, Stop and Drain both end in stopImpl
+	halted      int32 // 1 once Stop stopped the cron, which is not started again by the election
+
+	wg         *sync.WaitGroup
+	background sync.WaitGroup
 
 	logger           *logger
 	quitSignalCtx    context.Context
 	quitSignalCancel context.CancelFunc
 	rootPathInDocker string
+	hostProcPath     string
 	testMode         bool
+	test             testOptions
+	testFailed       int32 // 1 if any job failed in test mode
+	benchmark        *benchmark
+
+	metrics  *metrics
+	events   *eventBus
+	audit    *auditLog
+	server   *http.Server
+	jobsMu   sync.RWMutex
+	reloadMu sync.Mutex
+
+	settingsMu sync.RWMutex
+	settings   settings  // the settings of the loaded configs, replaced as a whole by the reload
+	loading    *settings // the settings of the configs being reloaded, nil if not reloading
+
+	states    *stateStore
+	heartbeat heartbeatOptions
+	sentry    *sentryReporter
+	statsd    *statsdClient
+
+	arguments []string
+	configs   []string
 }
 
 func NewTask(log *logger) *Task {
-	return &Task{
-		Cron:            cron.New(cron.WithParser(cron.NewParser(cron.SecondOptional|cron.Minute|cron.Hour|cron.Dom|cron.Month|cron.Dow|cron.Descriptor)), cron.WithLogger(log)),
-		Jobs:            nil,
-		wg:              &sync.WaitGroup{},
-		stoppingTimeout: 3_000,
-		logger:          log,
+	t := &Task{
+		Cron:        cron.New(cron.WithParser(cronParser), cron.WithLogger(log)),
+		Jobs:        nil,
+		wg:          &sync.WaitGroup{},
+		stopTimeout: defaultStoppingTimeout,
+		orphans:     "warn",
+		logger:      log,
+		metrics:     newMetrics(),
+		events:      newEventBus(),
+		audit:       &auditLog{logger: log},
+		states:      &stateStore{states: map[string]*jobState{}},
 	}
+	t.registerMetrics()
+	if log.console != nil && !log.options.Quiet {
+		log.console.watch(t.running.list)
+	}
+
+	return t
 }
 
-func (t *Task) AddJob(configFile string, jobs ...*job) error {
-	for _, j := range jobs {
+func (t *Task) AddJob(configFile string, jobs ...*job) (err error) {
+	var added, created []*job
+	names := map[string]bool{}
+	// the log directories checked once for the jobs
+	logDirs := map[string]error{}
+	defer func() { // the jobs scheduled before the error
+		if err != nil {
+			t.removeJobs(created)
+		}
+	}()
+	for i, j := range jobs {
+		definition, err := t.jobDefinition(j)
+		if err != nil {
+			return fmt.Errorf("schedule: \"%s\" error: %w", j.Schedule, err)
+		}
 		if j.Schedule == "" {
 			return fmt.Errorf("schedule required")
 		}
@@ -49,31 +115,269 @@ func (t *Task) AddJob(configFile string, jobs ...*job) error {
 			return fmt.Errorf("command of schedule: \"%s\" required", j.Schedule)
 		}
 
+		if j.CommandTemplate {
+			if _, err := template.New("command").Funcs(templateFuncs).Parse(j.Command); err != nil {
+				return fmt.Errorf("command of schedule: \"%s\" error: %w", j.Schedule, err)
+			}
+		}
+
+		if tpl := j.MessageTemplate; tpl != "" {
+			if _, err := template.New("message").Funcs(templateFuncs).Parse(tpl); err != nil {
+				return fmt.Errorf("message_template of schedule: \"%s\" error: %w", j.Schedule, err)
+			}
+		}
+
+		for i := range j.Webhooks {
+			if err := j.Webhooks[i].parse(); err != nil {
+				return fmt.Errorf("webhooks[%d] of schedule: \"%s\" error: %w", i, j.Schedule, err)
+			}
+		}
+
+		if j.Lock != "" && !containsString(lockBackends, j.Lock) {
+			return fmt.Errorf("lock of schedule: \"%s\" must be %v, yours: %s", j.Schedule, lockBackends, j.Lock)
+		}
+
+		if j.CatchUp != "" && !containsString(catchUpPolicies, j.CatchUp) {
+			return fmt.Errorf("catch_up of schedule: \"%s\" must be %v, yours: %s", j.Schedule, catchUpPolicies, j.CatchUp)
+		}
+
 		j.task = t
-		if err := j.makeLogger(t.logger); err != nil {
+		if err := j.checkHostNamespaces(); err != nil {
 			return err
 		}
 
+		if j.Shell != "" && !containsString(shellInterpreters, j.Shell) {
+			return fmt.Errorf("shell of schedule: \"%s\" must be %v, yours: %s", j.Schedule, shellInterpreters, j.Shell)
+		} else if j.Shell != "" && j.Shell != "sh" && t.InDocker() {
+			return fmt.Errorf("shell of schedule: \"%s\" must be sh in docker mode, yours: %s", j.Schedule, j.Shell)
+		} else if j.ScriptStdin && j.shellName() == "cmd" {
+			return fmt.Errorf("script_stdin of schedule: \"%s\" is not supported by cmd", j.Schedule)
+		}
+
+		if j.Type != "" && !containsString(jobTypes, j.Type) {
+			return fmt.Errorf("type of schedule: \"%s\" must be %v, yours: %s", j.Schedule, jobTypes, j.Type)
+		} else if j.Type == "shell" {
+			j.Type = ""
+		} else if j.Type == "docker-exec" {
+			if err := j.Container.validate(); err != nil {
+				return fmt.Errorf("schedule: \"%s\" error: %w", j.Schedule, err)
+			}
+		} else if j.Type == "compose-exec" {
+			if err := j.Compose.validate(); err != nil {
+				return fmt.Errorf("schedule: \"%s\" error: %w", j.Schedule, err)
+			}
+		} else if j.Type == "kubectl-exec" {
+			if err := j.Kubernetes.validate(); err != nil {
+				return fmt.Errorf("schedule: \"%s\" error: %w", j.Schedule, err)
+			}
+		}
+
+		if j.DSTPolicy != "" && !containsString(dstPolicies, j.DSTPolicy) {
+			return fmt.Errorf("dst_policy of schedule: \"%s\" must be %v, yours: %s", j.Schedule, dstPolicies, j.DSTPolicy)
+		}
+
+		if _, ok := opsgeniePriorities[j.Severity]; j.Severity != "" && !ok {
+			return fmt.Errorf("severity of schedule: \"%s\" must be [critical, error, warning, info], yours: %s", j.Schedule, j.Severity)
+		}
+
+		// the log files are opened on the first run, a bad directory fails the loading
+		for _, path := range []string{j.StdoutLog, j.StderrLog} {
+			if path == "" {
+				continue
+			}
+			dir := filepath.Dir(path)
+			if _, ok := logDirs[dir]; !ok {
+				logDirs[dir] = checkLogDir(dir)
+			}
+			if err := logDirs[dir]; err != nil {
+				return fmt.Errorf("log of schedule: \"%s\" error: %w", j.Schedule, err)
+			}
+		}
+
+		if j.Name == "" {
+			j.Name = fmt.Sprintf("%s-%d", filepath.Base(configFile), i+1)
+		}
+		if !j.matchTags(t.tags) {
+			t.logger.Info("job skipped by tags", "name", j.Name, "tags", j.Tags)
+			continue
+		}
+		// the names identify the jobs for the states, locks and the commands like trigger
+		if other := t.loadedJob(j.Name); other != nil {
+			return fmt.Errorf("name \"%s\" of schedule: \"%s\" is already used by the job of %s", j.Name, j.Schedule, other.configFile)
+		} else if names[j.Name] {
+			return fmt.Errorf("name \"%s\" of schedule: \"%s\" is duplicated", j.Name, j.Schedule)
+		}
+		names[j.Name] = true
+
+		previous, unchanged := t.reloadedJob(configFile, j.Name, definition)
+		if unchanged {
+			added = append(added, previous)
+			continue
+		}
+
+		j.task = t
+		j.configFile = configFile
+		j.definition = definition
+		if previous != nil { // the history is kept, the states are kept by the state store
+			j.history = previous.history
+		}
+		if j.history == nil {
+			j.history = newRunHistory(defaultHistorySize)
+		}
+		j.state = t.states.get(configFile, j.Name)
+		if j.AlertAfterFailures <= 0 {
+			j.AlertAfterFailures = 1
+		}
+		j.makeLogger()
+		if t.reloading != nil && fromConfigs(configFile) {
+			atomic.StoreInt32(&j.pending, 1)
+		}
+
 		if err := t.createCronJob(configFile, j); err != nil {
 			return err
 		}
+		created = append(created, j)
+
+		t.logger.Info("add job", "name", j.Name, "schedule", j.Schedule, "command", t.logger.command(j.Command))
+		added = append(added, j)
+		if t.reloading != nil && fromConfigs(configFile) {
+			t.reloading.added = append(t.reloading.added, j)
+		}
+	}
+
+	// the jobs of the configs are swapped in by the reload at once
+	if t.reloading != nil && fromConfigs(configFile) {
+		t.reloading.jobs = append(t.reloading.jobs, added...)
+		return nil
+	}
+	t.jobsMu.Lock()
+	t.Jobs = append(t.Jobs, added...)
+	t.jobsMu.Unlock()
+
+	return nil
+}
+
+// loadedJob returns the job of the name, while reloading the previous jobs of the configs are
+// not counted, which are replaced by the jobs reloaded
+func (t *Task) loadedJob(name string) *job {
+	if t.reloading == nil {
+		return t.findJob(name)
+	}
+	for _, j := range t.reloading.jobs {
+		if j.Name == name {
+			return j
+		}
+	}
+	if j := t.findJob(name); j != nil && !fromConfigs(j.configFile) {
+		return j
+	}
+	return nil
+}
+
+func (t *Task) findJob(name string) *job {
+	t.jobsMu.RLock()
+	defer t.jobsMu.RUnlock()
+
+	for _, j := range t.Jobs {
+		if j.Name == name {
+			return j
+		}
+	}
+	return nil
+}
 
-		t.logger.Info("add job", "schedule", j.Schedule, "command", truncateText(j.Command, 40))
+// TriggerJob runs the job out of schedule, the running mode of the job is respected.
+func (t *Task) TriggerJob(name, actor, source string) error {
+	j := t.findJob(name)
+	if j == nil {
+		return fmt.Errorf("job \"%s\" not found", name)
 	}
 
-	t.Jobs = append(t.Jobs, jobs...)
+	if t.isDraining() {
+		return fmt.Errorf("draining, job \"%s\" not triggered", name)
+	} else if atomic.LoadInt32(&t.halted) == 1 {
+		return fmt.Errorf("stopping, job \"%s\" not triggered", name)
+	} else if t.isPaused() {
+		return fmt.Errorf("paused, job \"%s\" not triggered", name)
+	}
+
+	if j.state.data().Disabled {
+		return fmt.Errorf("job \"%s\" is disabled", name)
+	}
+
+	entry := t.Cron.Entry(j.id)
+	if entry.WrappedJob == nil {
+		return fmt.Errorf("job \"%s\" is not scheduled", name)
+	}
+
+	t.audit.record("trigger", actor, source, j.Name, "")
+	t.logger.Info("trigger job", "name", j.Name, "actor", actor, "source", source)
+	if j.Debounce > 0 {
+		pending := j.debouncer.trigger(time.Duration(j.Debounce), entry.WrappedJob.Run)
+		t.logger.Info("trigger debounced", "name", j.Name, "debounce", j.Debounce.String(), "pending", pending)
+		return nil
+	}
+	go entry.WrappedJob.Run()
+	return nil
+}
+
+// SetJobEnabled enables or disables the job at runtime, which is kept across reloads by the state store.
+func (t *Task) SetJobEnabled(name string, enabled bool, actor, source string) error {
+	j := t.findJob(name)
+	if j == nil {
+		return fmt.Errorf("job \"%s\" not found", name)
+	}
+
+	action := "enable"
+	if !enabled {
+		action = "disable"
+	}
+	if !j.state.setDisabled(!enabled) {
+		return fmt.Errorf("job \"%s\" is already %sd", name, action)
+	}
+	t.saveStates()
 
+	t.audit.record(action, actor, source, j.Name, "")
+	t.logger.Info(action+" job", "name", j.Name, "actor", actor, "source", source)
+	t.events.publish(eventJobEnabled, j.Name, map[string]any{"enabled": enabled})
 	return nil
 }
 
+// stopDebouncers drops the pending triggers of all jobs, they would run after the cron stopped
+func (t *Task) stopDebouncers() {
+	t.jobsMu.RLock()
+	defer t.jobsMu.RUnlock()
+	for _, j := range t.Jobs {
+		j.debouncer.stop()
+	}
+}
+
+func (t *Task) removeJobs(jobs []*job) {
+	removing := map[*job]bool{}
+	for _, j := range jobs {
+		t.Cron.Remove(j.id)
+		j.deleteShellFile()
+		j.debouncer.stop()
+		removing[j] = true
+	}
+
+	t.jobsMu.Lock()
+	defer t.jobsMu.Unlock()
+	var remained []*job
+	for _, j := range t.Jobs {
+		if !removing[j] {
+			remained = append(remained, j)
+		}
+	}
+	t.Jobs = remained
+}
+
 func (t *Task) Start() {
 	if t.testMode {
 		t.startTest()
 		return
 	}
 
-	t.Cron.Start()
-	t.logger.Info("cron start")
 	t.wg.Add(1)
 
 	if t.quitSignalCancel != nil {
@@ -81,6 +385,26 @@ func (t *Task) Start() {
 	}
 
 	t.quitSignalCtx, t.quitSignalCancel = context.WithCancel(context.Background())
+	t.handleOrphans()
+	if t.benchmark != nil {
+		t.benchmark.startedAt = time.Now()
+	}
+	if t.getSettings().LeaderElection.Lock != "" {
+		if err := t.startElection(t.quitSignalCtx); err != nil {
+			panic(err.Error())
+		}
+	} else {
+		t.Cron.Start()
+		t.logger.Info("cron start")
+	}
+	t.startHeartbeat(t.quitSignalCtx)
+	t.startClockWatch(t.quitSignalCtx)
+	t.startDiscovery()
+	t.startSystemdWatchdog(t.quitSignalCtx)
+	if t.benchmark != nil {
+		t.startBenchmark(t.quitSignalCtx)
+	}
+	t.notifySystemd(sdReady)
 }
 
 func (t *Task) startTest() {
@@ -91,11 +415,13 @@ func (t *Task) startTest() {
 	}
 	t.quitSignalCtx, t.quitSignalCancel = context.WithCancel(context.Background())
 
+	jobs, err := t.testJobs()
+	if err != nil {
+		panic(err.Error())
+	}
 	go func() {
 		defer t.stopTest()
-		for _, j := range t.Jobs {
-			j.Run()
-		}
+		t.runTests(jobs)
 	}()
 }
 
@@ -104,6 +430,9 @@ func (t *Task) InDocker() bool {
 }
 
 func (t *Task) stopImpl(ctx context.Context) {
+	if !atomic.CompareAndSwapInt32(&t.stopping, 0, 1) {
+		return
+	}
 	defer t.wg.Done()
 	defer func() { // delete all temporary shell files
 		for _, j := range t.Jobs {
@@ -115,25 +444,48 @@ func (t *Task) stopImpl(ctx context.Context) {
 		t.quitSignalCancel()
 	}
 
-for1:
-	for atomic.LoadInt64(&t.runningCount) > 0 {
-		select {
-		case <-ctx.Done():
-			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
-				t.logger.Error(fmt.Errorf(""), "cron jobs force quiting")
-			}
-			break for1
-		default:
-			time.Sleep(100 * time.Second)
-		}
+	t.stopDebouncers()
+	if err := t.running.wait(ctx); errors.Is(err, context.DeadlineExceeded) {
+		t.logger.Error(fmt.Errorf("%d jobs still running", t.running.count()), "cron jobs force quiting")
 	}
 
+	t.waitBackground(backgroundTimeout)
+	t.flushStates()
+	t.stopServer()
+	t.audit.close()
+	t.sentry.flush()
+	t.statsd.close()
 	t.logger.Info("all jobs quit")
+	if t.logger.console != nil {
+		t.logger.console.close()
+	}
+}
+
+// stoppingTimeout is the default stopping timeout of jobs
+func (t *Task) stoppingTimeout() time.Duration {
+	if timeout := t.getSettings().StoppingTimeout; timeout > 0 {
+		return time.Duration(timeout)
+	}
+	return t.stopTimeout
+}
+
+// maxStoppingTimeout is the longest stopping timeout of the jobs, the jobs are killed before it
+func (t *Task) maxStoppingTimeout() time.Duration {
+	t.jobsMu.RLock()
+	defer t.jobsMu.RUnlock()
+
+	timeout := t.stoppingTimeout()
+	for _, j := range t.Jobs {
+		if jobTimeout := j.stoppingTimeout(); jobTimeout > timeout {
+			timeout = jobTimeout
+		}
+	}
+	return timeout + time.Second
 }
 
 func (t *Task) stopTest() {
 	// waiting for all job finish, force quit after stoppingTimeout
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(t.stoppingTimeout)*time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), t.maxStoppingTimeout())
 	defer cancel()
 
 	t.stopImpl(ctx)
@@ -145,10 +497,14 @@ func (t *Task) Stop() {
 		return
 	}
 
+	t.notifySystemd(sdStopping)
+	t.reloadMu.Lock()
+	atomic.StoreInt32(&t.halted, 1)
 	stoppingCtx := t.Cron.Stop()
+	t.reloadMu.Unlock()
 
 	// waiting for all job finish, force quit after stoppingTimeout
-	ctx, cancel := context.WithTimeout(stoppingCtx, time.Duration(t.stoppingTimeout)*time.Millisecond)
+	ctx, cancel := context.WithTimeout(stoppingCtx, t.maxStoppingTimeout())
 	defer cancel()
 
 	t.stopImpl(ctx)
@@ -156,31 +512,52 @@ func (t *Task) Stop() {
 
 func (t *Task) ListenStopSignal(callback func()) {
 	go func() {
-		ch := make(chan os.Signal)
-		signal.Notify(ch, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, stopSignals...)
 		<-ch
 		callback()
 	}()
 }
 
+// ListenReloadSignal reloads the arguments and configs when SIGHUP received
+func (t *Task) ListenReloadSignal() {
+	go func() {
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, syscall.SIGHUP)
+		for range ch {
+			t.audit.record("reload", "unknown", auditSourceSignal, "", "SIGHUP")
+			if err := t.Reload(); err != nil {
+				t.logger.Error(err, "reload fail")
+				t.reportError(err, "reload fail")
+			}
+		}
+	}()
+}
+
 func (t *Task) createCronJob(configFile string, job *job) error {
 	var jobWrappers []cron.JobWrapper
-	jobWrappers = append(jobWrappers, cron.Recover(job.logger))
+	jobWrappers = append(jobWrappers, recoverPanic(job))
 
 	// wrap the running mode
 	switch job.RunningMode {
 	case "delay":
-		jobWrappers = append(jobWrappers, cron.DelayIfStillRunning(job.logger))
+		jobWrappers = append(jobWrappers, cron.DelayIfStillRunning(jobLogger{job}))
 	case "skip":
-		jobWrappers = append(jobWrappers, cron.SkipIfStillRunning(job.logger))
+		jobWrappers = append(jobWrappers, skipIfStillRunning(job))
 	}
 
-	id, err := t.Cron.AddJob(job.Schedule, cron.NewChain(jobWrappers...).Then(job))
+	schedule, err := job.parseSchedule()
 	if err != nil {
 		return fmt.Errorf("invalid schedule [%s]: %w", job.Schedule, err)
 	}
 
-	job.id = id
+	if t.benchmark != nil {
+		jobWrappers = append([]cron.JobWrapper{t.benchmark.measure(schedule)}, jobWrappers...)
+	}
+	job.id = t.Cron.Schedule(schedule, cron.NewChain(jobWrappers...).Then(job))
+	if job.Type != "" || job.ScriptStdin { // the command is not read from the shell file
+		return nil
+	}
 
 	// put job.command to a temporary shell file
 	if configFile == "argument" {
@@ -188,15 +565,36 @@ func (t *Task) createCronJob(configFile string, job *job) error {
 	}
 
 	if t.testMode {
-		job.shellFile = filepath.Join(filepath.Dir(configFile), fmt.Sprintf(".test-%s-%d.sh", filepath.Base(configFile), id))
+		job.shellFile = filepath.Join(filepath.Dir(configFile), fmt.Sprintf(".test-%s-%d%s", filepath.Base(configFile), job.id, job.shellExt()))
 	} else {
-		job.shellFile = filepath.Join(filepath.Dir(configFile), fmt.Sprintf(".%s-%d.sh", filepath.Base(configFile), id))
+		job.shellFile = filepath.Join(filepath.Dir(configFile), fmt.Sprintf(".%s-%d%s", filepath.Base(configFile), job.id, job.shellExt()))
 	}
-	job.saveShellFile()
 
 	return nil
 }
 
+// goBackground runs fn in a goroutine, like pings and mails, which are waited for before quitting
+func (t *Task) goBackground(fn func()) {
+	t.background.Add(1)
+	go func() {
+		defer t.background.Done()
+		fn()
+	}()
+}
+
+func (t *Task) waitBackground(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		t.background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		t.logger.Error(fmt.Errorf("timeout"), "background tasks quit unfinished")
+	}
+}
+
 func (t *Task) Wait() {
 	t.wg.Wait()
 }
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const telegramAPI = "https://api.telegram.org"
+
+type telegramSettings struct {
+	Token  string   `json:"token" yaml:"token"` // the token of bot
+	ChatID string   `json:"chat_id" yaml:"chat_id"`
+	Events []string `json:"events" yaml:"events"` // [start, success, failure, recovery, circuit_open, budget_exceeded], [failure, recovery, circuit_open, budget_exceeded] by default
+}
+
+func (s telegramSettings) enabled() bool {
+	return s.Token != "" && s.ChatID != ""
+}
+
+// override returns the settings with the non-empty fields of other
+func (s telegramSettings) override(other telegramSettings) telegramSettings {
+	if other.Token != "" {
+		s.Token = other.Token
+	}
+	if other.ChatID != "" {
+		s.ChatID = other.ChatID
+	}
+	if len(other.Events) > 0 {
+		s.Events = other.Events
+	}
+	return s
+}
+
+type telegramNotifier struct {
+	settings telegramSettings
+}
+
+var telegramClient = &http.Client{Timeout: 10 * time.Second}
+
+func (t telegramNotifier) accepts(event string) bool {
+	return acceptsEvent(t.settings.Events, event, notifyFailure, notifyRecovery, notifyCircuit, notifyBudget)
+}
+
+func (t telegramNotifier) retries() int {
+	return defaultWebhookRetries
+}
+
+func (t telegramNotifier) String() string {
+	return "telegram " + t.settings.ChatID
+}
+
+func telegramText(n *notification) string {
+	if n.Message != "" {
+		return n.Message
+	}
+
+	text := fmt.Sprintf("[%s] %s on %s", n.Event, n.Job, n.Hostname)
+	if n.Event != notifyStart {
+		text += fmt.Sprintf("\nexit code: %d, duration: %s", n.ExitCode, n.Duration)
+	}
+	if n.Error != "" {
+		text += "\nerror: " + n.Error
+	}
+	if n.Output != "" {
+		text += "\n\n" + n.Output
+	}
+	return text
+}
+
+func (t telegramNotifier) send(n *notification) error {
+	body, err := json.Marshal(map[string]any{
+		"chat_id":                  t.settings.ChatID,
+		"text":                     telegramText(n),
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, telegramAPI+"/bot"+t.settings.Token+"/sendMessage", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := telegramClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	if !result.OK {
+		return fmt.Errorf("telegram error: %s", result.Description)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// commandData is the data of command_template, like
+//
+//	pg_dump -f /backup/{{.JobName}}-{{.Date "2006-01-02"}}.sql
+//	curl -H "X-Run-ID: {{.RunID}}" https://example.com/{{.Hostname}}
+type commandData struct {
+	JobName  string
+	RunID    string
+	Hostname string
+	Time     time.Time // when the run started
+}
+
+// Date formats the time of the run by the layout of golang
+func (d commandData) Date(layout string) string {
+	return d.Time.Format(layout)
+}
+
+// expandCommand returns the command of the run and the shell file of it, which is written for the run if expanded
+func (job *job) expandCommand(runID string) (script, shellFile string, err error) {
+	if !job.CommandTemplate {
+		return job.Command, job.shellFile, job.saveShellFile()
+	}
+
+	hostname, _ := os.Hostname()
+	script, err = renderTemplate(job.Command, commandData{JobName: job.Name, RunID: runID, Hostname: hostname, Time: time.Now()})
+	if err != nil || job.shellFile == "" {
+		return script, job.shellFile, err
+	}
+
+	// the runs may overlap, every run has its own shell file
+	ext := filepath.Ext(job.shellFile)
+	shellFile = strings.TrimSuffix(job.shellFile, ext) + "-" + runID + ext
+	path := shellFile
+	if job.task.InDocker() {
+		path = filepath.Join(job.task.rootPathInDocker, shellFile)
+	}
+	if err = os.WriteFile(path, job.shellScript(script), 0o600); err != nil {
+		return "", "", err
+	}
+	return script, shellFile, nil
+}
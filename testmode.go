@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// testOptions are the options of --test, which runs the jobs once and quits
+type testOptions struct {
+	Jobs     []string      // run only the jobs by names, all by default
+	Parallel bool          // run the jobs at once instead of one by one
+	Timeout  time.Duration // kill the runs longer than it, the timeout of job if shorter, disabled if 0
+}
+
+// TestFailed returns true if any job failed or was skipped in test mode, for the exit code
+func (t *Task) TestFailed() bool {
+	return atomic.LoadInt32(&t.testFailed) == 1
+}
+
+// testJobs returns the jobs selected by --test-jobs, in the order of configs
+func (t *Task) testJobs() ([]*job, error) {
+	if len(t.test.Jobs) <= 0 {
+		return t.Jobs, nil
+	}
+	for _, name := range t.test.Jobs {
+		if t.findJob(name) == nil {
+			return nil, fmt.Errorf("job %s of --test-jobs not found", name)
+		}
+	}
+
+	var jobs []*job
+	for _, j := range t.Jobs {
+		if containsString(t.test.Jobs, j.Name) {
+			jobs = append(jobs, j)
+		}
+	}
+	return jobs, nil
+}
+
+// runTests runs the jobs once, then logs the result of every job
+func (t *Task) runTests(jobs []*job) {
+	for _, j := range jobs {
+		if timeout := t.test.Timeout.Milliseconds(); timeout > 0 && (j.Timeout <= 0 || j.Timeout > timeout) {
+			j.Timeout = timeout
+		}
+	}
+
+	if t.test.Parallel {
+		var wg sync.WaitGroup
+		for _, j := range jobs {
+			wg.Add(1)
+			go func(j *job) {
+				defer wg.Done()
+				j.Run()
+			}(j)
+		}
+		wg.Wait()
+	} else {
+		for _, j := range jobs {
+			j.Run()
+		}
+	}
+
+	var failed []string
+	for _, j := range jobs {
+		record, ok := j.history.last()
+		if !ok {
+			t.logger.Error(fmt.Errorf("skipped"), "test failed", "name", j.Name)
+			failed = append(failed, j.Name)
+		} else if !record.success() {
+			err := fmt.Errorf("exit code %d", record.ExitCode)
+			if record.Error != "" {
+				err = fmt.Errorf("%s", record.Error)
+			}
+			t.logger.Error(err, "test failed", "name", j.Name, "run_id", record.RunID, "duration", record.Duration.String())
+			failed = append(failed, j.Name)
+		} else {
+			t.logger.Info("test passed", "name", j.Name, "run_id", record.RunID, "duration", record.Duration.String())
+		}
+	}
+	if len(failed) > 0 {
+		atomic.StoreInt32(&t.testFailed, 1)
+	}
+	t.logger.Info("test finished", "passed", len(jobs)-len(failed), "failed", len(failed), "failed_jobs", failed)
+}
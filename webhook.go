@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const defaultWebhookRetries = 3
+
+type webhookConfig struct {
+	URL     string            `json:"url" yaml:"url"`
+	Method  string            `json:"method" yaml:"method"` // POST by default
+	Events  []string          `json:"events" yaml:"events"` // [start, success, failure, recovery, circuit_open, budget_exceeded], [failure, recovery, circuit_open, budget_exceeded] by default
+	Headers map[string]string `json:"headers" yaml:"headers"`
+	Payload string            `json:"payload" yaml:"payload"` // a text/template of the body, like {"text": {{json .Job}}}; the json of notification by default
+	Retries *int              `json:"retries" yaml:"retries"` // 3 by default
+	Timeout int64             `json:"timeout" yaml:"timeout"` // milliseconds, 10s by default
+
+	payload *template.Template // parsed from Payload when loading
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+func acceptsEvent(events []string, event string, defaults ...string) bool {
+	if len(events) <= 0 {
+		events = defaults
+	}
+	for _, e := range events {
+		if strings.EqualFold(e, event) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w webhookConfig) accepts(event string) bool {
+	return acceptsEvent(w.Events, event, notifyFailure, notifyRecovery, notifyCircuit, notifyBudget)
+}
+
+func (w webhookConfig) retries() int {
+	if w.Retries == nil {
+		return defaultWebhookRetries
+	}
+	return *w.Retries
+}
+
+func (w webhookConfig) String() string {
+	return "webhook " + redactURL(w.URL)
+}
+
+// parse parses the payload template once when loading, so an invalid template fails the config instead of the sends
+func (w *webhookConfig) parse() error {
+	if w.Payload == "" {
+		return nil
+	}
+	tpl, err := template.New("payload").Funcs(templateFuncs).Parse(w.Payload)
+	if err != nil {
+		return fmt.Errorf("invalid payload template: %w", err)
+	}
+	w.payload = tpl
+	return nil
+}
+
+func (w webhookConfig) body(n *notification) ([]byte, error) {
+	if w.Payload == "" {
+		return json.Marshal(n)
+	} else if w.payload == nil {
+		if err := w.parse(); err != nil {
+			return nil, err
+		}
+	}
+
+	var body bytes.Buffer
+	if err := w.payload.Execute(&body, n); err != nil {
+		return nil, fmt.Errorf("render payload fail: %w", err)
+	}
+	return body.Bytes(), nil
+}
+
+func (w webhookConfig) send(n *notification) error {
+	body, err := w.body(n)
+	if err != nil {
+		return err
+	}
+
+	method := w.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequest(strings.ToUpper(method), w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.Headers {
+		req.Header.Set(k, v)
+	}
+
+	timeout := 10 * time.Second
+	if w.Timeout > 0 {
+		timeout = time.Duration(w.Timeout) * time.Millisecond
+	}
+	return doRequest(&http.Client{Timeout: timeout}, req)
+}
+
+// doRequest sends the request, returns an error if the status code is not 2xx
+func doRequest(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("status code: %d", resp.StatusCode)
+	}
+	return nil
+}
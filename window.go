@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+)
+
+// windowSchedule fires once a day at a random time within the window, like @between 02:00-04:00
+// or TZ=Asia/Shanghai @between 22:00-02:00. The time is picked for each day by the job name, kept across reloads.
+type windowSchedule struct {
+	from, to time.Duration // since 00:00
+	loc      *time.Location
+	seed     string
+}
+
+// parseWindow parses the schedule of @between, returns nil if not a window
+func parseWindow(expr, seed string) (*windowSchedule, error) {
+	loc, fields, err := scheduleFields(expr)
+	if err != nil || len(fields) <= 0 || fields[0] != "@between" {
+		return nil, err
+	}
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("@between must be like @between 02:00-04:00")
+	}
+
+	from, to, ok := strings.Cut(fields[1], "-")
+	if !ok {
+		return nil, fmt.Errorf("@between must be like @between 02:00-04:00, yours: %s", fields[1])
+	}
+	s := &windowSchedule{loc: loc, seed: seed}
+	if s.from, err = parseClock(from); err != nil {
+		return nil, err
+	}
+	if s.to, err = parseClock(to); err != nil {
+		return nil, err
+	}
+	if s.to == s.from {
+		return nil, fmt.Errorf("the window of @between is empty: %s", fields[1])
+	}
+	return s, nil
+}
+
+// parseClock parses the time of day like 02:00 or 02:00:30, returns the duration since 00:00
+func parseClock(s string) (time.Duration, error) {
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid time of day %s, must be like 02:00 or 02:00:30", s)
+}
+
+func (s *windowSchedule) Next(t time.Time) time.Time {
+	// from the day before, the window may be across the midnight
+	y, m, d := t.In(s.loc).Date()
+	for i := -1; i <= 2; i++ {
+		if next := s.pick(time.Date(y, m, d+i, 0, 0, 0, 0, s.loc)); next.After(t) {
+			return next.In(t.Location())
+		}
+	}
+	return time.Time{}
+}
+
+// pick returns the random time within the window of the day, always the same for the day
+func (s *windowSchedule) pick(day time.Time) time.Time {
+	length := s.to - s.from
+	if length < 0 {
+		length += 24 * time.Hour
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s.seed + day.Format("2006-01-02")))
+	offset := time.Duration(h.Sum64()%uint64(length/time.Second)) * time.Second
+	// by the wall clock, the day of DST is not 24 hours
+	y, m, d := day.Date()
+	return time.Date(y, m, d, 0, 0, int((s.from+offset)/time.Second), 0, s.loc)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindowNext(t *testing.T) {
+	tests := []struct {
+		expr  string
+		from  string
+		start string // the earliest and the latest of the next firing
+		end   string
+	}{
+		{"@between 02:00-04:00", "2024-05-01T00:00:00Z", "2024-05-01T02:00:00Z", "2024-05-01T04:00:00Z"},
+		{"@between 02:00-04:00", "2024-05-01T04:00:00Z", "2024-05-02T02:00:00Z", "2024-05-02T04:00:00Z"},
+		{"@between 22:00-02:00", "2024-05-01T12:00:00Z", "2024-05-01T22:00:00Z", "2024-05-02T02:00:00Z"}, // across the midnight
+		{"@between 22:00-02:00", "2024-05-01T00:30:00Z", "2024-05-01T00:30:00Z", "2024-05-02T02:00:00Z"}, // the window of the day before
+		{"@between 02:00:30-02:00:40", "2024-05-01T00:00:00Z", "2024-05-01T02:00:30Z", "2024-05-01T02:00:40Z"},
+		{"TZ=Asia/Shanghai @between 02:00-04:00", "2024-05-01T00:00:00Z", "2024-05-01T18:00:00Z", "2024-05-01T20:00:00Z"},
+		{"TZ=America/New_York @between 02:00-04:00", "2024-03-10T05:00:00Z", "2024-03-10T07:00:00Z", "2024-03-10T08:00:00Z"}, // 02:00-03:00 skipped
+	}
+	for _, test := range tests {
+		t.Run(test.expr+" from "+test.from, func(t *testing.T) {
+			schedule, err := parseWindow(test.expr, "test")
+			if err != nil || schedule == nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			from, _ := time.Parse(time.RFC3339, test.from)
+			start, _ := time.Parse(time.RFC3339, test.start)
+			end, _ := time.Parse(time.RFC3339, test.end)
+			got := schedule.Next(from)
+			if !got.After(from) || got.Before(start) || !got.Before(end) {
+				t.Errorf("Next() = %s, want in [%s, %s)", got.UTC().Format(time.RFC3339), test.start, test.end)
+			}
+			// the same time of the day, whenever asked
+			if again := schedule.Next(from.Add(time.Second)); again.Before(got) || (got.Sub(from) > time.Second && !again.Equal(got)) {
+				t.Errorf("Next() = %s after a second, want %s", again.UTC().Format(time.RFC3339), got.UTC().Format(time.RFC3339))
+			}
+		})
+	}
+}
+
+func TestWindowSeed(t *testing.T) {
+	// the time is kept for the job name, and differs with the names
+	a, _ := parseWindow("@between 00:00-23:00", "backup")
+	b, _ := parseWindow("@between 00:00-23:00", "backup")
+	c, _ := parseWindow("@between 00:00-23:00", "cleanup")
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	differs := false
+	for i := 0; i < 10; i++ {
+		at := from.AddDate(0, 0, i)
+		if !a.Next(at).Equal(b.Next(at)) {
+			t.Errorf("Next(%s) differs for the same name", at.Format(time.RFC3339))
+		}
+		differs = differs || !a.Next(at).Equal(c.Next(at))
+	}
+	if !differs {
+		t.Errorf("Next() is the same for the different names")
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		expr    string
+		window  bool
+		wantErr bool
+	}{
+		{"@daily", false, false},
+		{"0 2 * * *", false, false},
+		{"@between 02:00-04:00", true, false},
+		{"TZ=UTC @between 23:30-00:30", true, false},
+		{"@between", true, true},
+		{"@between 02:00", true, true},
+		{"@between 02:00-02:00", true, true},
+		{"@between 02:00-25:00", true, true},
+		{"@between 02:00-04:00 daily", true, true},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			schedule, err := parseWindow(test.expr, "test")
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error = %v, want error %v", err, test.wantErr)
+			}
+			if !test.wantErr && (schedule != nil) != test.window {
+				t.Errorf("window = %v, want %v", schedule != nil, test.window)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"runtime"
+)
+
+// recoverPanic is the same as cron.Recover, but reports the panic to sentry.
+func recoverPanic(j *job) cron.JobWrapper {
+	return func(next cron.Job) cron.Job {
+		return cron.FuncJob(func() {
+			defer func() {
+				if r := recover(); r != nil {
+					const size = 64 << 10
+					buf := make([]byte, size)
+					buf = buf[:runtime.Stack(buf, false)]
+					err, ok := r.(error)
+					if !ok {
+						err = fmt.Errorf("%v", r)
+					}
+					j.log().Error(err, "panic", "name", j.Name, "stack", "...\n"+string(buf))
+					j.reportError("fatal", err, "job panic", map[string]any{"stack": string(buf)})
+				}
+			}()
+			next.Run()
+		})
+	}
+}
+
+// skipIfStillRunning is the same as cron.SkipIfStillRunning, but publishes the skipping.
+func skipIfStillRunning(j *job) cron.JobWrapper {
+	return func(next cron.Job) cron.Job {
+		var ch = make(chan struct{}, 1)
+		ch <- struct{}{}
+		return cron.FuncJob(func() {
+			select {
+			case v := <-ch:
+				next.Run()
+				ch <- v
+			default:
+				j.skip(j.log(), "still running", nil)
+			}
+		})
+	}
+}
+
+// jobLogger is the cron.Logger of the wrappers, which logs by the logger of the job opened on the first use
+type jobLogger struct {
+	job *job
+}
+
+func (l jobLogger) Info(msg string, kv ...any) {
+	l.job.log().Info(msg, kv...)
+}
+
+func (l jobLogger) Error(err error, msg string, kv ...any) {
+	l.job.log().Error(err, msg, kv...)
+}